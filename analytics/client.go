@@ -7,6 +7,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+
+	"github.com/coveo/go-coveo/auth"
+	"github.com/coveo/go-coveo/endpoint"
 )
 
 const (
@@ -53,35 +56,51 @@ type Client interface {
 type Config struct {
 	// Token is the token used to log into the service remotly
 	Token string
+	// TokenSource provides the token of every request, Token is used if nil
+	TokenSource auth.TokenSource
 	// User agent is the http user agent sent to the service
 	UserAgent string
 	// IP is used if you want to specify an origin IP to the client
 	IP string
 	// Endpoint is used if you want to use custom endpoints (dev,staging,testing)
 	Endpoint string
+	// Region is the region of the organization, used when Endpoint is empty
+	Region endpoint.Region
+	// Environment is the environment of the organization, used when Endpoint
+	// is empty
+	Environment endpoint.Environment
 }
 
 // NewClient return a capable Coveo Usage Analytics service client. It currently
-// uses V15 of the API.
+// uses V15 of the API. If the region and environment cannot be resolved to an
+// endpoint, every call of the client returns the resolution error.
 func NewClient(c Config) Client {
+	var err error
 	if len(c.Endpoint) == 0 {
-		c.Endpoint = EndpointProduction
+		if len(c.Region) == 0 && len(c.Environment) == 0 {
+			c.Endpoint = EndpointProduction
+		} else {
+			c.Endpoint, err = endpoint.Resolve(endpoint.Analytics, c.Environment, c.Region)
+		}
+	}
+	if c.TokenSource == nil {
+		c.TokenSource = auth.StaticToken(c.Token)
 	}
 	return &client{
-		token:      c.Token,
 		endpoint:   c.Endpoint,
-		httpClient: http.DefaultClient,
+		httpClient: auth.NewHTTPClient(c.TokenSource),
 		useragent:  c.UserAgent,
-		ip:         c.IP}
+		ip:         c.IP,
+		err:        err}
 }
 
 type client struct {
 	httpClient *http.Client
-	token      string
 	endpoint   string
 	useragent  string
 	ip         string
 	cookies    []*http.Cookie
+	err        error
 }
 
 // NewSearchEvent creates a new SearchEvent which can then be altered
@@ -210,6 +229,10 @@ func (c *client) GetStatus() (*StatusResponse, error) {
 }
 
 func (c *client) sendEventRequest(path string, event interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+
 	var buf bytes.Buffer
 	err := json.NewEncoder(&buf).Encode(event)
 	if err != nil {
@@ -226,7 +249,6 @@ func (c *client) sendEventRequest(path string, event interface{}) error {
 			req.AddCookie(cookie)
 		}
 	}
-	req.Header.Add("Authorization", "Bearer "+c.token)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accepts", "application/json")
 	req.Header.Set("User-Agent", c.useragent)
@@ -256,6 +278,10 @@ func (c *client) sendEventRequest(path string, event interface{}) error {
 }
 
 func (c *client) sendRawEventRequest(method string, path string, body string) error {
+	if c.err != nil {
+		return c.err
+	}
+
 	req, err := http.NewRequest(method, c.endpoint+path, strings.NewReader(body))
 	if err != nil {
 		return err
@@ -266,7 +292,6 @@ func (c *client) sendRawEventRequest(method string, path string, body string) er
 			req.AddCookie(cookie)
 		}
 	}
-	req.Header.Add("Authorization", "Beared "+c.token)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accepts", "application/json")
 	req.Header.Set("User-Agent", c.useragent)
@@ -0,0 +1,173 @@
+// Package auth provides the tokens used to authenticate the requests sent to
+// the Coveo services. Token sources are consulted on every request, so keys
+// can be rotated without rebuilding the clients.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource returns the token to send with a request. Implementations must
+// be safe for concurrent use.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// Refresher is implemented by the token sources able to get a new token,
+// clients call Refresh and retry once when a request is unauthorized.
+type Refresher interface {
+	Refresh() error
+}
+
+// StaticToken returns a TokenSource always returning the same token
+func StaticToken(token string) TokenSource {
+	return staticToken(token)
+}
+
+type staticToken string
+
+func (t staticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+// EnvToken returns a TokenSource reading the token from an environment
+// variable on every call
+func EnvToken(name string) TokenSource {
+	return envToken(name)
+}
+
+type envToken string
+
+func (t envToken) Token() (string, error) {
+	token := os.Getenv(string(t))
+	if len(token) == 0 {
+		return "", fmt.Errorf("the environment variable %s is empty", string(t))
+	}
+	return token, nil
+}
+
+// FileToken returns a TokenSource reading the token from a file. The file is
+// read again whenever its modification time changes, or when Refresh is
+// called. Leading and trailing spaces are removed from the token.
+func FileToken(path string) *FileTokenSource {
+	return &FileTokenSource{path: path}
+}
+
+// FileTokenSource is a TokenSource watching a file, see FileToken
+type FileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+// Token returns the content of the file, reading it if it changed
+func (f *FileTokenSource) Token() (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.token) != 0 && info.ModTime().Equal(f.modTime) {
+		return f.token, nil
+	}
+	return f.read(info.ModTime())
+}
+
+// Refresh reads the file again even if it did not change
+func (f *FileTokenSource) Refresh() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.read(info.ModTime())
+	return err
+}
+
+func (f *FileTokenSource) read(modTime time.Time) (string, error) {
+	content, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(content))
+	if len(token) == 0 {
+		return "", fmt.Errorf("the token file %s is empty", f.path)
+	}
+
+	f.token = token
+	f.modTime = modTime
+	return token, nil
+}
+
+// RefreshFunc gets a new token, for example from an OAuth token endpoint. A
+// zero expiry means the token does not expire.
+type RefreshFunc func() (token string, expiry time.Time, err error)
+
+// RefreshableToken returns a TokenSource calling refresh to get its first
+// token, when the token expires and when Refresh is called.
+func RefreshableToken(refresh RefreshFunc) *RefreshableTokenSource {
+	return &RefreshableTokenSource{refresh: refresh}
+}
+
+// RefreshableTokenSource is a TokenSource backed by a RefreshFunc, see
+// RefreshableToken
+type RefreshableTokenSource struct {
+	refresh RefreshFunc
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// expiryDelta is how long before its expiry a token is refreshed, so that it
+// does not expire while the request is sent.
+const expiryDelta = 10 * time.Second
+
+// Token returns the current token, refreshing it first if it is missing or
+// about to expire
+func (r *RefreshableTokenSource) Token() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.token) != 0 && (r.expiry.IsZero() || time.Now().Add(expiryDelta).Before(r.expiry)) {
+		return r.token, nil
+	}
+	if err := r.refreshLocked(); err != nil {
+		return "", err
+	}
+	return r.token, nil
+}
+
+// Refresh gets a new token
+func (r *RefreshableTokenSource) Refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refreshLocked()
+}
+
+func (r *RefreshableTokenSource) refreshLocked() error {
+	token, expiry, err := r.refresh()
+	if err != nil {
+		return err
+	}
+	if len(token) == 0 {
+		return errors.New("the refresh function returned an empty token")
+	}
+
+	r.token = token
+	r.expiry = expiry
+	return nil
+}
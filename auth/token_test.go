@@ -0,0 +1,124 @@
+package auth_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/auth"
+)
+
+func TestFileTokenReloadsChangedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	write := func(token string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(token), 0600); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	assertToken := func(source auth.TokenSource, expected string) {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+		if token != expected {
+			t.Errorf("unexpected token.  expected %v, actual %v", expected, token)
+		}
+	}
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write(" first\n", modTime)
+	source := auth.FileToken(path)
+	assertToken(source, "first")
+
+	// The file is not read again while its modification time is the same
+	write("second", modTime)
+	assertToken(source, "first")
+
+	write("third", modTime.Add(time.Minute))
+	assertToken(source, "third")
+
+	// Refresh reads the file even if it did not change
+	write("fourth", modTime.Add(time.Minute))
+	if err := source.Refresh(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	assertToken(source, "fourth")
+
+	write("", modTime.Add(2*time.Minute))
+	if _, err := source.Token(); err == nil {
+		t.Errorf("expected an error for an empty token file")
+	}
+}
+
+func TestEnvTokenMissingVariable(t *testing.T) {
+	const name = "GO_COVEO_AUTH_TEST_TOKEN"
+	os.Unsetenv(name)
+	source := auth.EnvToken(name)
+
+	if _, err := source.Token(); err == nil {
+		t.Errorf("expected an error for a missing variable")
+	}
+
+	os.Setenv(name, "mytoken")
+	defer os.Unsetenv(name)
+	token, err := source.Token()
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if token != "mytoken" {
+		t.Errorf("unexpected token.  expected %v, actual %v", "mytoken", token)
+	}
+}
+
+func TestRefreshableTokenExpiry(t *testing.T) {
+	refreshes := 0
+	expiry := time.Now().Add(time.Hour)
+	source := auth.RefreshableToken(func() (string, time.Time, error) {
+		refreshes++
+		return "token", expiry, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := source.Token(); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("unexpected refreshes.  expected %v, actual %v", 1, refreshes)
+	}
+
+	// A token about to expire is refreshed before it is used
+	expiry = time.Now().Add(5 * time.Second)
+	if err := source.Refresh(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if refreshes != 3 {
+		t.Errorf("unexpected refreshes.  expected %v, actual %v", 3, refreshes)
+	}
+
+	// A zero expiry never expires
+	expiry = time.Time{}
+	if err := source.Refresh(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if refreshes != 4 {
+		t.Errorf("unexpected refreshes.  expected %v, actual %v", 4, refreshes)
+	}
+}
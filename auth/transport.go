@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// Transport is an http.RoundTripper adding the token of its source as a bearer
+// token to every request. When a request is unauthorized and the source is a
+// Refresher, the token is refreshed and the request is sent once more.
+type Transport struct {
+	// Source provides the token of every request
+	Source TokenSource
+	// Base is the RoundTripper used to send the requests,
+	// http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// NewHTTPClient returns an http.Client authenticating its requests with the
+// token source
+func NewHTTPClient(source TokenSource) *http.Client {
+	return &http.Client{Transport: &Transport{Source: source}}
+}
+
+// RoundTrip sends the request with the token of the source
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	refresher, ok := t.Source.(Refresher)
+	if resp.StatusCode != http.StatusUnauthorized || !ok {
+		return resp, nil
+	}
+	// The body was consumed by the first attempt, the request can only be
+	// sent again if it can be rewound.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	if err := refresher.Refresh(); err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := req
+	if req.Body != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+	return t.send(retry)
+}
+
+func (t *Transport) send(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("Authorization", "Bearer "+token)
+	return t.base().RoundTrip(authenticated)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
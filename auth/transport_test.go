@@ -0,0 +1,44 @@
+package auth_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/auth"
+)
+
+func TestTransportRetriesAfterRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	tokens := []string{"old", "new"}
+	source := auth.RefreshableToken(func() (string, time.Time, error) {
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token, time.Time{}, nil
+	})
+
+	resp, err := auth.NewHTTPClient(source).Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status.  expected %v, actual %v", http.StatusOK, resp.StatusCode)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "payload" {
+		t.Fatalf("unexpected body.  expected %v, actual %v", "payload", string(body))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/coveo/go-coveo/analytics"
+	"github.com/coveo/go-coveo/endpoint"
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/search"
+)
+
+// Config is the content of the configuration file
+type Config struct {
+	// Default is the name of the profile used when none is given
+	Default  string             `json:"default"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Profile holds the credentials and endpoints of an organization. Empty
+// endpoints are resolved from the region and environment.
+type Profile struct {
+	OrganizationID    string               `json:"organizationId"`
+	SearchToken       string               `json:"searchToken"`
+	APIKey            string               `json:"apiKey"`
+	AnalyticsToken    string               `json:"analyticsToken"`
+	UserAgent         string               `json:"userAgent"`
+	Region            endpoint.Region      `json:"region"`
+	Environment       endpoint.Environment `json:"environment"`
+	SearchEndpoint    string               `json:"searchEndpoint"`
+	PushEndpoint      string               `json:"pushEndpoint"`
+	PlatformEndpoint  string               `json:"platformEndpoint"`
+	AnalyticsEndpoint string               `json:"analyticsEndpoint"`
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("COVEO_CONFIG"); len(path) != 0 {
+		return path
+	}
+	home := os.Getenv("HOME")
+	if len(home) == 0 {
+		home = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(home, ".coveo", "config.json")
+}
+
+// loadProfile reads the named profile from the configuration file. A missing
+// file is only an error if a profile was explicitly asked for. Credentials
+// found in the environment take precedence over the profile.
+func loadProfile(path string, name string) (Profile, error) {
+	profile := Profile{}
+
+	content, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err) && len(name) == 0:
+	case err != nil:
+		return profile, err
+	default:
+		config := Config{}
+		if err := json.Unmarshal(content, &config); err != nil {
+			return profile, fmt.Errorf("invalid configuration file %s: %v", path, err)
+		}
+
+		if len(name) == 0 {
+			name = config.Default
+		}
+		if len(name) != 0 {
+			p, ok := config.Profiles[name]
+			if !ok {
+				return profile, fmt.Errorf("unknown profile %q in %s", name, path)
+			}
+			profile = p
+		}
+	}
+
+	overrideFromEnv(&profile.OrganizationID, "COVEO_ORGANIZATION_ID")
+	overrideFromEnv(&profile.SearchToken, "COVEO_SEARCH_TOKEN")
+	overrideFromEnv(&profile.APIKey, "COVEO_API_KEY")
+	overrideFromEnv(&profile.AnalyticsToken, "COVEO_ANALYTICS_TOKEN")
+	return profile, nil
+}
+
+func overrideFromEnv(value *string, name string) {
+	if v := os.Getenv(name); len(v) != 0 {
+		*value = v
+	}
+}
+
+func (p Profile) searchClient() (search.Client, error) {
+	return search.NewClient(search.Config{
+		Token:       p.SearchToken,
+		UserAgent:   p.UserAgent,
+		Endpoint:    p.SearchEndpoint,
+		Region:      p.Region,
+		Environment: p.Environment,
+	})
+}
+
+func (p Profile) pushClient() (pushapi.Client, error) {
+	return pushapi.NewClient(pushapi.Config{
+		OrganizationID:   p.OrganizationID,
+		APIKey:           p.APIKey,
+		Endpoint:         p.PushEndpoint,
+		PlatformEndpoint: p.PlatformEndpoint,
+		Region:           p.Region,
+		Environment:      p.Environment,
+	})
+}
+
+func (p Profile) analyticsClient() analytics.Client {
+	return analytics.NewClient(analytics.Config{
+		Token:       p.AnalyticsToken,
+		UserAgent:   p.UserAgent,
+		Endpoint:    p.AnalyticsEndpoint,
+		Region:      p.Region,
+		Environment: p.Environment,
+	})
+}
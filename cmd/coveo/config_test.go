@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `{
+	"default": "prod",
+	"profiles": {
+		"prod": {"organizationId": "prodorg", "apiKey": "prodkey", "region": "eu"},
+		"dev": {"organizationId": "devorg", "environment": "development"}
+	}
+}`
+
+func configFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "coveo")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadProfile(t *testing.T) {
+	path, cleanup := configFile(t, testConfig)
+	defer cleanup()
+
+	profile, err := loadProfile(path, "")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if profile.OrganizationID != "prodorg" || profile.APIKey != "prodkey" || profile.Region != "eu" {
+		t.Errorf("unexpected default profile.  expected prodorg, actual %+v", profile)
+	}
+
+	profile, err = loadProfile(path, "dev")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if profile.OrganizationID != "devorg" || profile.Environment != "development" {
+		t.Errorf("unexpected profile.  expected devorg, actual %+v", profile)
+	}
+
+	if _, err := loadProfile(path, "staging"); err == nil {
+		t.Errorf("expected an error for an unknown profile")
+	}
+}
+
+func TestLoadProfileFromEnvironment(t *testing.T) {
+	path, cleanup := configFile(t, testConfig)
+	defer cleanup()
+
+	os.Setenv("COVEO_API_KEY", "envkey")
+	defer os.Unsetenv("COVEO_API_KEY")
+
+	profile, err := loadProfile(path, "")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if profile.APIKey != "envkey" || profile.OrganizationID != "prodorg" {
+		t.Errorf("unexpected profile.  expected the key of the environment, actual %+v", profile)
+	}
+}
+
+func TestLoadProfileMissingFile(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "coveo-missing", "config.json")
+
+	// Without a profile asked for, the environment is enough
+	if _, err := loadProfile(path, ""); err != nil {
+		t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if _, err := loadProfile(path, "prod"); err == nil {
+		t.Errorf("expected an error for a profile of a missing file")
+	}
+}
+
+func TestLoadProfileInvalidFile(t *testing.T) {
+	path, cleanup := configFile(t, `{"profiles": `)
+	defer cleanup()
+
+	if _, err := loadProfile(path, ""); err == nil {
+		t.Errorf("expected an error for an invalid configuration file")
+	}
+}
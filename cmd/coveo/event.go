@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/coveo/go-coveo/analytics"
+)
+
+func runEvent(a *app, args []string) error {
+	flags := flag.NewFlagSet("event", flag.ExitOnError)
+	eventType := flags.String("type", "", "type of the events, search, click, custom or view")
+	file := flags.String("file", "-", "file containing the JSON events, - for stdin")
+	flags.Parse(args)
+
+	send, err := eventSender(a.profile.analyticsClient(), *eventType)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = decodeInput(*file, func(decoder *json.Decoder) error {
+		if err := send(decoder); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.output.message("sent", fmt.Sprintf("%d %s event(s)", count, *eventType))
+}
+
+// eventSender returns a function decoding a single event of the given type,
+// starting from the default values of the event, and sending it.
+func eventSender(client analytics.Client, eventType string) (func(*json.Decoder) error, error) {
+	switch eventType {
+	case "search":
+		return func(decoder *json.Decoder) error {
+			event := analytics.NewSearchEvent()
+			if err := decoder.Decode(event); err != nil {
+				return err
+			}
+			return client.SendSearchEvent(event)
+		}, nil
+	case "click":
+		return func(decoder *json.Decoder) error {
+			event := analytics.NewClickEvent()
+			if err := decoder.Decode(event); err != nil {
+				return err
+			}
+			return client.SendClickEvent(event)
+		}, nil
+	case "custom":
+		return func(decoder *json.Decoder) error {
+			event := analytics.NewCustomEvent()
+			if err := decoder.Decode(event); err != nil {
+				return err
+			}
+			return client.SendCustomEvent(event)
+		}, nil
+	case "view":
+		return func(decoder *json.Decoder) error {
+			event := analytics.NewViewEvent()
+			if err := decoder.Decode(event); err != nil {
+				return err
+			}
+			return client.SendViewEvent(event)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown event type %q, expected search, click, custom or view", eventType)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coveo/go-coveo/filesystem"
+	"github.com/coveo/go-coveo/incremental"
+)
+
+func runIndex(a *app, args []string) error {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	sourceID := flags.String("source", "", "source ID")
+	dir := flags.String("dir", ".", "directory to index")
+	include := flags.String("include", "", "comma-separated glob patterns of the files to index")
+	exclude := flags.String("exclude", "", "comma-separated glob patterns of the files and directories to leave out")
+	maxSize := flags.Int64("max-size", 0, "size in bytes above which files are left out, a limit fitting the push batches if 0 and no limit if negative")
+	statePath := flags.String("state", "", "file keeping the state of the indexed files, in the configuration directory if empty")
+	flags.Parse(args)
+
+	if len(*sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	if len(*statePath) == 0 {
+		*statePath = filepath.Join(filepath.Dir(defaultConfigPath()), "state", *sourceID+".json")
+	}
+	if err := os.MkdirAll(filepath.Dir(*statePath), 0700); err != nil {
+		return err
+	}
+
+	client, err := a.profile.pushClient()
+	if err != nil {
+		return err
+	}
+
+	store, err := incremental.OpenFileStore(*statePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	connector := &filesystem.Connector{
+		Root:        *dir,
+		Include:     splitList(*include),
+		Exclude:     splitList(*exclude),
+		MaxFileSize: *maxSize,
+		OnError: func(p string, err error) {
+			fmt.Fprintf(os.Stderr, "skipping %s: %v\n", p, err)
+		},
+	}
+	stats, err := connector.Sync(&incremental.Engine{Client: client, SourceID: *sourceID, Store: store})
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{{
+		strconv.Itoa(stats.New),
+		strconv.Itoa(stats.Changed),
+		strconv.Itoa(stats.Unchanged),
+		strconv.Itoa(stats.Deleted),
+	}}
+	return a.output.print(stats, []string{"NEW", "CHANGED", "UNCHANGED", "DELETED"}, rows)
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) != 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,101 @@
+// Command coveo runs search, push and analytics operations from a terminal.
+//
+// Usage:
+//
+//	coveo [-config file] [-profile name] [-output json|table] <command> [flags]
+//
+// The commands are:
+//
+//	search   run a query
+//	facet    list the values of a field
+//	push     push a document read from a file or stdin
+//	delete   delete a document
+//	index    push the files of a directory that changed since the last run
+//	event    send an analytics event read from a file or stdin
+//
+// Credentials and endpoints are read from a profile of the configuration
+// file, $HOME/.coveo/config.json by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	description string
+	run         func(a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"search": {"run a query", runSearch},
+	"facet":  {"list the values of a field", runFacet},
+	"push":   {"push a document read from a file or stdin", runPush},
+	"delete": {"delete a document", runDelete},
+	"index":  {"push the files of a directory that changed since the last run", runIndex},
+	"event":  {"send an analytics event read from a file or stdin", runEvent},
+}
+
+// app holds what every command needs, the selected profile and output
+type app struct {
+	profile Profile
+	output  *output
+}
+
+func main() {
+	flags := flag.NewFlagSet("coveo", flag.ExitOnError)
+	configPath := flags.String("config", defaultConfigPath(), "configuration file")
+	profileName := flags.String("profile", os.Getenv("COVEO_PROFILE"), "configuration profile, the default profile if empty")
+	format := flags.String("output", "table", "output format, json or table")
+	flags.Usage = func() { usage(flags) }
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		usage(flags)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "coveo: unknown command %q\n", flags.Arg(0))
+		usage(flags)
+		os.Exit(2)
+	}
+
+	out, err := newOutput(os.Stdout, *format)
+	if err != nil {
+		exit(err)
+	}
+
+	profile, err := loadProfile(*configPath, *profileName)
+	if err != nil {
+		exit(err)
+	}
+
+	if err := cmd.run(&app{profile: profile, output: out}, flags.Args()[1:]); err != nil {
+		exit(err)
+	}
+}
+
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage: coveo [flags] <command> [command flags]")
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flags.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].description)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "coveo: %v\n", err)
+	os.Exit(1)
+}
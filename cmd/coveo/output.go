@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case "json":
+		return &output{w: w, json: true}, nil
+	case "table":
+		return &output{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected json or table", format)
+	}
+}
+
+// print writes v as JSON, or the rows as a table under the headers
+func (o *output) print(v interface{}, headers []string, rows [][]string) error {
+	if o.json {
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// message writes a status message, as a JSON object when the output is JSON
+func (o *output) message(status string, detail string) error {
+	return o.print(map[string]string{"status": status, "detail": detail},
+		[]string{"STATUS", "DETAIL"}, [][]string{{status, detail}})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func runPush(a *app, args []string) error {
+	flags := flag.NewFlagSet("push", flag.ExitOnError)
+	sourceID := flags.String("source", "", "source ID")
+	file := flags.String("file", "-", "file containing the JSON documents, - for stdin")
+	flags.Parse(args)
+
+	client, err := a.profile.pushClient()
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	pushed := 0
+	pushErr := decodeInput(*file, func(decoder *json.Decoder) error {
+		document := pushapi.Document{}
+		if err := decoder.Decode(&document); err != nil {
+			return err
+		}
+
+		if _, err := client.PushDocument(document, *sourceID); err != nil {
+			rows = append(rows, []string{document.DocumentID, "failed"})
+			return fmt.Errorf("%s: %v", document.DocumentID, err)
+		}
+		rows = append(rows, []string{document.DocumentID, "pushed"})
+		pushed++
+		return nil
+	})
+
+	// The documents pushed before an error are reported too, they are in the
+	// source
+	if len(rows) != 0 {
+		if err := a.output.print(rowsToObjects(rows, "documentId", "status"), []string{"DOCUMENT", "STATUS"}, rows); err != nil {
+			return err
+		}
+	}
+	if pushErr != nil && pushed != 0 {
+		return fmt.Errorf("%v (%d documents pushed before the error)", pushErr, pushed)
+	}
+	return pushErr
+}
+
+func runDelete(a *app, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	sourceID := flags.String("source", "", "source ID")
+	documentID := flags.String("id", "", "ID of the document to delete")
+	children := flags.Bool("children", false, "also delete the children of the document")
+	flags.Parse(args)
+
+	client, err := a.profile.pushClient()
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteDocumentWithOptions(*documentID, *sourceID, pushapi.DeleteOptions{DeleteChildren: *children})
+	if err != nil {
+		return err
+	}
+	return a.output.message("deleted", *documentID)
+}
+
+// decodeInput calls decode for every JSON value of the file, or of stdin if
+// the path is -, until the end of the input is reached.
+func decodeInput(path string, decode func(*json.Decoder) error) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	decoder := json.NewDecoder(r)
+	count := 0
+	for decoder.More() {
+		if err := decode(decoder); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		return errors.New("no JSON value found in the input")
+	}
+	return nil
+}
+
+func rowsToObjects(rows [][]string, keys ...string) []map[string]string {
+	objects := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		objects[i] = map[string]string{}
+		for j, key := range keys {
+			objects[i][key] = row[j]
+		}
+	}
+	return objects
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pushServer accepts the documents, except the ones whose ID contains "bad"
+func pushServer(t *testing.T) (*httptest.Server, *[]string) {
+	pushed := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("documentId")
+		if r.URL.Path != "/myorg/sources/mysource/documents" || strings.Contains(id, "bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pushed = append(pushed, id)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return server, &pushed
+}
+
+func documentsFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "coveo")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	path := filepath.Join(dir, "documents.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func testApp(server *httptest.Server, out *bytes.Buffer) *app {
+	return &app{
+		profile: Profile{
+			OrganizationID:   "myorg",
+			PushEndpoint:     server.URL + "/",
+			PlatformEndpoint: server.URL + "/platform/",
+		},
+		output: &output{w: out, json: true},
+	}
+}
+
+func TestRunPush(t *testing.T) {
+	server, pushed := pushServer(t)
+	defer server.Close()
+	path, cleanup := documentsFile(t, `{"documentId": "file://a"} {"documentId": "file://b"}`)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	if err := runPush(testApp(server, out), []string{"-source", "mysource", "-file", path}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if len(*pushed) != 2 {
+		t.Errorf("unexpected documents pushed.  expected %v, actual %v", 2, *pushed)
+	}
+	rows := []map[string]string{}
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(rows) != 2 || rows[1]["documentId"] != "file://b" || rows[1]["status"] != "pushed" {
+		t.Errorf("unexpected output.  expected 2 pushed documents, actual %v", rows)
+	}
+}
+
+func TestRunPushReportsPartialProgress(t *testing.T) {
+	server, pushed := pushServer(t)
+	defer server.Close()
+	path, cleanup := documentsFile(t, `{"documentId": "file://a"} {"documentId": "file://bad"} {"documentId": "file://c"}`)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	err := runPush(testApp(server, out), []string{"-source", "mysource", "-file", path})
+	if err == nil || !strings.Contains(err.Error(), "file://bad") || !strings.Contains(err.Error(), "1 documents pushed") {
+		t.Errorf("unexpected error.  expected the failed document and the progress, actual %v", err)
+	}
+
+	if len(*pushed) != 1 {
+		t.Errorf("unexpected documents pushed.  expected %v, actual %v", 1, *pushed)
+	}
+	rows := []map[string]string{}
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	expected := []map[string]string{
+		{"documentId": "file://a", "status": "pushed"},
+		{"documentId": "file://bad", "status": "failed"},
+	}
+	if len(rows) != 2 || rows[0]["status"] != expected[0]["status"] || rows[1]["status"] != expected[1]["status"] {
+		t.Errorf("unexpected output.  expected %v, actual %v", expected, rows)
+	}
+}
+
+func TestRunPushEmptyInput(t *testing.T) {
+	server, _ := pushServer(t)
+	defer server.Close()
+	path, cleanup := documentsFile(t, "")
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	if err := runPush(testApp(server, out), []string{"-source", "mysource", "-file", path}); err == nil {
+		t.Errorf("expected an error for an empty input")
+	}
+	if out.Len() != 0 {
+		t.Errorf("unexpected output.  expected none, actual %v", out.String())
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		"":                {},
+		"*.txt":           {"*.txt"},
+		" *.txt , *.pdf,": {"*.txt", "*.pdf"},
+	}
+	for value, expected := range tests {
+		actual := splitList(value)
+		if strings.Join(actual, "|") != strings.Join(expected, "|") || len(actual) != len(expected) {
+			t.Errorf("unexpected items for %q.  expected %v, actual %v", value, expected, actual)
+		}
+	}
+}
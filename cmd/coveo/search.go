@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/coveo/go-coveo/search"
+)
+
+func runSearch(a *app, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	q := flags.String("q", "", "basic query expression")
+	aq := flags.String("aq", "", "advanced query expression")
+	cq := flags.String("cq", "", "constant query expression")
+	pipeline := flags.String("pipeline", "", "query pipeline")
+	numberOfResults := flags.Int("n", 10, "number of results")
+	firstResult := flags.Int("first", 0, "index of the first result")
+	flags.Parse(args)
+
+	client, err := a.profile.searchClient()
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Query(search.Query{
+		Q:               *q,
+		AQ:              *aq,
+		CQ:              *cq,
+		Pipeline:        *pipeline,
+		NumberOfResults: *numberOfResults,
+		FirstResult:     *firstResult,
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(response.Results))
+	for i, result := range response.Results {
+		rows[i] = []string{strconv.Itoa(*firstResult + i + 1), result.Title, result.URI}
+	}
+	if err := a.output.print(response, []string{"#", "TITLE", "URI"}, rows); err != nil {
+		return err
+	}
+
+	if !a.output.json {
+		fmt.Fprintf(a.output.w, "\n%d of %d results (%d ms)\n",
+			len(response.Results), response.TotalCount, response.Duration)
+	}
+	return nil
+}
+
+func runFacet(a *app, args []string) error {
+	flags := flag.NewFlagSet("facet", flag.ExitOnError)
+	field := flags.String("field", "", "field to list the values of, with the @ prefix")
+	maximumNumberOfValues := flags.Int("max", 10, "maximum number of values")
+	flags.Parse(args)
+
+	if len(*field) == 0 {
+		return errors.New("You need to provide a field")
+	}
+
+	client, err := a.profile.searchClient()
+	if err != nil {
+		return err
+	}
+
+	values, err := client.ListFacetValues(*field, *maximumNumberOfValues)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(values.Values))
+	for i, value := range values.Values {
+		rows[i] = []string{value.Value, strconv.Itoa(value.NumberOfResults)}
+	}
+	return a.output.print(values, []string{"VALUE", "RESULTS"}, rows)
+}
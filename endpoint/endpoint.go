@@ -0,0 +1,104 @@
+// Package endpoint resolves the base URL of the Coveo services for the region
+// and the deployment environment an organization lives in.
+package endpoint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is the region hosting an organization
+type Region string
+
+const (
+	// RegionUS is the United States region, the default one
+	RegionUS Region = "us"
+	// RegionEU is the European Union region
+	RegionEU Region = "eu"
+	// RegionAU is the Australian region
+	RegionAU Region = "au"
+	// RegionCA is the Canadian region
+	RegionCA Region = "ca"
+)
+
+// Environment is the deployment environment of an organization
+type Environment string
+
+const (
+	// Production is the production environment, the default one
+	Production Environment = "production"
+	// Staging is the staging environment, only available in the US region
+	Staging Environment = "staging"
+	// Development is the development environment, only available in the US region
+	Development Environment = "development"
+	// HIPAA is the HIPAA compliant production environment, only available in
+	// the US region
+	HIPAA Environment = "hipaa"
+)
+
+// Service is a Coveo service with its own base URL
+type Service string
+
+const (
+	// Search is the Search API, its URL ends with /rest/search/
+	Search Service = "search"
+	// Push is the Push API, its URL ends with /push/v1/organizations/
+	Push Service = "push"
+	// Analytics is the Usage Analytics write API, its URL ends with
+	// /rest/ua/v15/analytics/
+	Analytics Service = "analytics"
+	// Platform is the platform API, its URL ends with /rest/organizations/
+	Platform Service = "platform"
+)
+
+var environmentSuffixes = map[Environment]string{
+	Production:  "",
+	Staging:     "stg",
+	Development: "dev",
+	HIPAA:       "hipaa",
+}
+
+var regionSuffixes = map[Region]string{
+	RegionUS: "",
+	RegionEU: "-eu",
+	RegionAU: "-au",
+	RegionCA: "-ca",
+}
+
+// Resolve returns the base URL of the service for the environment and region.
+// An empty environment or region means Production or RegionUS. Regions other
+// than RegionUS are only available in Production.
+func Resolve(s Service, env Environment, region Region) (string, error) {
+	if len(env) == 0 {
+		env = Production
+	}
+	if len(region) == 0 {
+		region = RegionUS
+	}
+
+	envSuffix, ok := environmentSuffixes[Environment(strings.ToLower(string(env)))]
+	if !ok {
+		return "", fmt.Errorf("unknown environment %q", env)
+	}
+	regionSuffix, ok := regionSuffixes[Region(strings.ToLower(string(region)))]
+	if !ok {
+		return "", fmt.Errorf("unknown region %q", region)
+	}
+	if len(envSuffix) != 0 && len(regionSuffix) != 0 {
+		return "", fmt.Errorf("the %s environment is not available in the %s region", env, region)
+	}
+
+	suffix := envSuffix + regionSuffix
+	switch s {
+	case Search:
+		return "https://platform" + suffix + ".cloud.coveo.com/rest/search/", nil
+	case Push:
+		return "https://api" + suffix + ".cloud.coveo.com/push/v1/organizations/", nil
+	case Analytics:
+		return "https://analytics" + suffix + ".cloud.coveo.com/rest/ua/v15/analytics/", nil
+	case Platform:
+		return "https://platform" + suffix + ".cloud.coveo.com/rest/organizations/", nil
+	default:
+		return "", fmt.Errorf("unknown service %q", s)
+	}
+}
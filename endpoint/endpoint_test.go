@@ -0,0 +1,37 @@
+package endpoint_test
+
+import (
+	"testing"
+
+	"github.com/coveo/go-coveo/endpoint"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		service     endpoint.Service
+		environment endpoint.Environment
+		region      endpoint.Region
+		expected    string
+	}{
+		{endpoint.Search, "", "", "https://platform.cloud.coveo.com/rest/search/"},
+		{endpoint.Push, endpoint.Production, endpoint.RegionEU, "https://api-eu.cloud.coveo.com/push/v1/organizations/"},
+		{endpoint.Analytics, endpoint.HIPAA, endpoint.RegionUS, "https://analyticshipaa.cloud.coveo.com/rest/ua/v15/analytics/"},
+		{endpoint.Platform, endpoint.Development, "", "https://platformdev.cloud.coveo.com/rest/organizations/"},
+	}
+
+	for _, test := range tests {
+		actual, err := endpoint.Resolve(test.service, test.environment, test.region)
+		if err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+		if actual != test.expected {
+			t.Errorf("unexpected endpoint.  expected %v, actual %v", test.expected, actual)
+		}
+	}
+}
+
+func TestResolveUnavailable(t *testing.T) {
+	if _, err := endpoint.Resolve(endpoint.Search, endpoint.HIPAA, endpoint.RegionAU); err == nil {
+		t.Fatalf("expected an error for the hipaa environment in the au region")
+	}
+}
@@ -0,0 +1,104 @@
+// Package evaluation measures the relevance of search results offline, by
+// running the queries of a judgment list through a search client and
+// comparing the returned documents to the judged ones.
+package evaluation
+
+import (
+	"errors"
+
+	"github.com/coveo/go-coveo/search"
+)
+
+// DefaultK is the rank at which the metrics are computed when none is given
+const DefaultK = 10
+
+// Evaluator runs the queries of a judgment list through a search client
+type Evaluator struct {
+	// Client is the search client used to run the queries
+	Client search.Client
+	// K is the rank at which the metrics are computed, DefaultK if zero
+	K int
+	// BaseQuery is used as a template for every query sent, the Q, Pipeline
+	// and NumberOfResults values are overwritten.
+	BaseQuery search.Query
+}
+
+// Run runs every query of the judgment list in the given pipeline, or in the
+// default pipeline if empty, and returns the report of the results.
+func (e *Evaluator) Run(judgments *JudgmentList, pipeline string) (*Report, error) {
+	if e.Client == nil {
+		return nil, errors.New("You need a search client")
+	}
+
+	k := e.k()
+	report := &Report{Pipeline: pipeline, K: k}
+	for _, q := range judgments.Queries() {
+		query := e.BaseQuery
+		query.Q = q
+		query.Pipeline = pipeline
+		query.NumberOfResults = k
+
+		response, err := e.Client.Query(query)
+		if err != nil {
+			return nil, err
+		}
+
+		ranked := make([]string, len(response.Results))
+		for i, result := range response.Results {
+			ranked[i] = result.URI
+		}
+
+		report.Queries = append(report.Queries, QueryReport{
+			Query:   q,
+			Results: ranked,
+			Metrics: Compute(ranked, judgments.Grades(q), k),
+		})
+	}
+	report.Mean = mean(report.Queries)
+
+	return report, nil
+}
+
+// Compare runs the judgment list against a baseline and a candidate pipeline
+// and returns the differences between both.
+func (e *Evaluator) Compare(judgments *JudgmentList, baseline, candidate string) (*Comparison, error) {
+	baselineReport, err := e.Run(judgments, baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	candidateReport, err := e.Run(judgments, candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	return Compare(baselineReport, candidateReport), nil
+}
+
+func (e *Evaluator) k() int {
+	if e.K <= 0 {
+		return DefaultK
+	}
+	return e.K
+}
+
+func mean(queries []QueryReport) Metrics {
+	m := Metrics{}
+	if len(queries) == 0 {
+		return m
+	}
+
+	for _, q := range queries {
+		m.NDCG += q.Metrics.NDCG
+		m.MRR += q.Metrics.MRR
+		m.Precision += q.Metrics.Precision
+		m.Recall += q.Metrics.Recall
+	}
+
+	n := float64(len(queries))
+	m.NDCG /= n
+	m.MRR /= n
+	m.Precision /= n
+	m.Recall /= n
+	return m
+}
@@ -0,0 +1,138 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coveo/go-coveo/evaluation"
+	"github.com/coveo/go-coveo/search"
+)
+
+// fakeSearch returns the URIs listed for the pipeline and query of each
+// request, and records the requests
+type fakeSearch struct {
+	search.Client
+	results map[string]map[string][]string
+	queries []search.Query
+	err     error
+}
+
+func (s *fakeSearch) Query(q search.Query) (*search.Response, error) {
+	s.queries = append(s.queries, q)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	response := &search.Response{}
+	for _, uri := range s.results[q.Pipeline][q.Q] {
+		response.Results = append(response.Results, search.Result{URI: uri})
+	}
+	return response, nil
+}
+
+func judgments() *evaluation.JudgmentList {
+	return evaluation.NewJudgmentList([]evaluation.Judgment{
+		{Query: "foo", DocumentURI: "http://a", Grade: 3},
+		{Query: "foo", DocumentURI: "http://b", Grade: 1},
+		{Query: "bar", DocumentURI: "http://c", Grade: 2},
+	})
+}
+
+func TestEvaluatorRun(t *testing.T) {
+	client := &fakeSearch{results: map[string]map[string][]string{
+		"mypipeline": {
+			"foo": {"http://a", "http://x"},
+			"bar": {"http://c"},
+		},
+	}}
+	e := &evaluation.Evaluator{Client: client, K: 2, BaseQuery: search.Query{AQ: "@source==mysource"}}
+
+	report, err := e.Run(judgments(), "mypipeline")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if len(client.queries) != 2 {
+		t.Fatalf("unexpected queries.  expected %v, actual %+v", 2, client.queries)
+	}
+	for _, q := range client.queries {
+		if q.Pipeline != "mypipeline" || q.NumberOfResults != 2 || q.AQ != "@source==mysource" {
+			t.Errorf("unexpected query.  expected the pipeline, K and base query, actual %+v", q)
+		}
+	}
+
+	if report.Pipeline != "mypipeline" || report.K != 2 || len(report.Queries) != 2 {
+		t.Fatalf("unexpected report.  expected 2 queries at K 2, actual %+v", report)
+	}
+	foo := report.Queries[0]
+	if foo.Query != "foo" || len(foo.Results) != 2 || foo.Results[0] != "http://a" {
+		t.Errorf("unexpected query report.  expected the results of foo, actual %+v", foo)
+	}
+	expectedFoo := evaluation.Compute([]string{"http://a", "http://x"}, judgments().Grades("foo"), 2)
+	if foo.Metrics != expectedFoo {
+		t.Errorf("unexpected metrics.  expected %+v, actual %+v", expectedFoo, foo.Metrics)
+	}
+	expectedBar := evaluation.Compute([]string{"http://c"}, judgments().Grades("bar"), 2)
+	assertFloat(t, "mean ndcg", (expectedFoo.NDCG+expectedBar.NDCG)/2, report.Mean.NDCG)
+	assertFloat(t, "mean recall", (expectedFoo.Recall+expectedBar.Recall)/2, report.Mean.Recall)
+}
+
+func TestEvaluatorRunFails(t *testing.T) {
+	e := &evaluation.Evaluator{Client: &fakeSearch{err: errors.New("the search API is unavailable")}}
+	if _, err := e.Run(judgments(), ""); err == nil {
+		t.Errorf("expected the error of the search client")
+	}
+	if _, err := (&evaluation.Evaluator{}).Run(judgments(), ""); err == nil {
+		t.Errorf("expected an error without a search client")
+	}
+}
+
+func TestEvaluatorCompare(t *testing.T) {
+	client := &fakeSearch{results: map[string]map[string][]string{
+		"baseline": {
+			"foo": {"http://x", "http://a"},
+			"bar": {"http://c"},
+		},
+		"candidate": {
+			"foo": {"http://a", "http://b"},
+		},
+	}}
+	e := &evaluation.Evaluator{Client: client, K: 2}
+
+	c, err := e.Compare(judgments(), "baseline", "candidate")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if c.Baseline.Pipeline != "baseline" || c.Candidate.Pipeline != "candidate" {
+		t.Errorf("unexpected reports.  expected baseline and candidate, actual %v and %v", c.Baseline.Pipeline, c.Candidate.Pipeline)
+	}
+	if len(c.Queries) != 2 || c.Queries[0].Query != "foo" || c.Queries[1].Query != "bar" {
+		t.Fatalf("unexpected queries.  expected foo and bar, actual %+v", c.Queries)
+	}
+
+	foo := c.Queries[0]
+	assertFloat(t, "foo mrr", 0.5, foo.Baseline.MRR)
+	assertFloat(t, "foo mrr", 1, foo.Candidate.MRR)
+	assertFloat(t, "foo mrr delta", 0.5, foo.Delta.MRR)
+	// The candidate returns nothing for bar
+	bar := c.Queries[1]
+	assertFloat(t, "bar mrr delta", -1, bar.Delta.MRR)
+	assertFloat(t, "mean mrr delta", c.Candidate.Mean.MRR-c.Baseline.Mean.MRR, c.Delta.MRR)
+}
+
+func TestCompareMissingQueries(t *testing.T) {
+	baseline := &evaluation.Report{
+		K:       10,
+		Mean:    evaluation.Metrics{MRR: 1},
+		Queries: []evaluation.QueryReport{{Query: "foo", Metrics: evaluation.Metrics{MRR: 1}}},
+	}
+	candidate := &evaluation.Report{K: 10}
+
+	c := evaluation.Compare(baseline, candidate)
+	if len(c.Queries) != 1 || c.Queries[0].Candidate != (evaluation.Metrics{}) {
+		t.Fatalf("unexpected queries.  expected foo compared with zero metrics, actual %+v", c.Queries)
+	}
+	assertFloat(t, "mrr delta", -1, c.Queries[0].Delta.MRR)
+	assertFloat(t, "mean mrr delta", -1, c.Delta.MRR)
+}
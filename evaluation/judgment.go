@@ -0,0 +1,111 @@
+package evaluation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Judgment is a single relevance judgment, the grade given to a document for
+// a query. A grade of zero or less means the document is not relevant.
+type Judgment struct {
+	Query       string  `json:"query"`
+	DocumentURI string  `json:"documentUri"`
+	Grade       float64 `json:"grade"`
+}
+
+// JudgmentList holds the judgments of every query, keeping the order in which
+// the queries were first seen.
+type JudgmentList struct {
+	queries []string
+	grades  map[string]map[string]float64
+}
+
+// NewJudgmentList creates a JudgmentList from a slice of judgments. When the
+// same document is judged twice for a query, the last grade wins.
+func NewJudgmentList(judgments []Judgment) *JudgmentList {
+	l := &JudgmentList{grades: map[string]map[string]float64{}}
+	for _, j := range judgments {
+		l.Add(j)
+	}
+	return l
+}
+
+// Add adds a judgment to the list
+func (l *JudgmentList) Add(j Judgment) {
+	grades, ok := l.grades[j.Query]
+	if !ok {
+		grades = map[string]float64{}
+		l.grades[j.Query] = grades
+		l.queries = append(l.queries, j.Query)
+	}
+	grades[j.DocumentURI] = j.Grade
+}
+
+// Queries returns the judged queries in the order they were added
+func (l *JudgmentList) Queries() []string {
+	return l.queries
+}
+
+// Grades returns the grade of every judged document for the query, keyed by
+// document URI.
+func (l *JudgmentList) Grades(query string) map[string]float64 {
+	return l.grades[query]
+}
+
+// ReadJudgments reads a judgment list made of "query, document URI, grade"
+// records separated by comma. A first record that does not have a numeric
+// grade is considered a header and skipped. Lines starting with # are
+// comments, they are not counted in the record numbers of the errors.
+func ReadJudgments(r io.Reader, comma rune) (*JudgmentList, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	l := &JudgmentList{grades: map[string]map[string]float64{}}
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		grade, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			if n == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("record %d: invalid grade %q", n, record[2])
+		}
+
+		l.Add(Judgment{
+			Query:       strings.TrimSpace(record[0]),
+			DocumentURI: strings.TrimSpace(record[1]),
+			Grade:       grade,
+		})
+	}
+}
+
+// LoadJudgments reads a judgment list file. Files with a .tsv extension are
+// tab separated, every other file is comma separated.
+func LoadJudgments(path string) (*JudgmentList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	comma := ','
+	if strings.ToLower(filepath.Ext(path)) == ".tsv" {
+		comma = '\t'
+	}
+	return ReadJudgments(f, comma)
+}
@@ -0,0 +1,116 @@
+package evaluation
+
+import (
+	"math"
+	"sort"
+)
+
+// Metrics are the relevance measures computed for a ranked list of documents
+type Metrics struct {
+	NDCG      float64 `json:"ndcg"`
+	MRR       float64 `json:"mrr"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// Compute returns the metrics at rank k of the ranked document URIs given the
+// grades of the judged documents. Documents that were not judged are
+// considered not relevant.
+func Compute(ranked []string, grades map[string]float64, k int) Metrics {
+	return Metrics{
+		NDCG:      NDCG(ranked, grades, k),
+		MRR:       ReciprocalRank(ranked, grades, k),
+		Precision: Precision(ranked, grades, k),
+		Recall:    Recall(ranked, grades, k),
+	}
+}
+
+// NDCG returns the normalized discounted cumulative gain at rank k, using the
+// exponential gain 2^grade - 1.
+func NDCG(ranked []string, grades map[string]float64, k int) float64 {
+	ideal := make([]float64, 0, len(grades))
+	for _, grade := range grades {
+		ideal = append(ideal, grade)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(ideal)))
+
+	idcg := dcg(ideal, k)
+	if idcg == 0 {
+		return 0
+	}
+
+	gains := make([]float64, len(ranked))
+	for i, uri := range ranked {
+		gains[i] = grades[uri]
+	}
+	return dcg(gains, k) / idcg
+}
+
+// ReciprocalRank returns the inverse of the rank of the first relevant
+// document found in the first k documents, or zero if there is none. Its mean
+// over all queries is the MRR.
+func ReciprocalRank(ranked []string, grades map[string]float64, k int) float64 {
+	for i, uri := range truncate(ranked, k) {
+		if grades[uri] > 0 {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// Precision returns the fraction of the first k documents that are relevant
+func Precision(ranked []string, grades map[string]float64, k int) float64 {
+	if k <= 0 {
+		return 0
+	}
+	return float64(countRelevant(truncate(ranked, k), grades)) / float64(k)
+}
+
+// Recall returns the fraction of the relevant documents found in the first k
+// documents
+func Recall(ranked []string, grades map[string]float64, k int) float64 {
+	relevant := 0
+	for _, grade := range grades {
+		if grade > 0 {
+			relevant++
+		}
+	}
+	if relevant == 0 {
+		return 0
+	}
+	return float64(countRelevant(truncate(ranked, k), grades)) / float64(relevant)
+}
+
+func dcg(gains []float64, k int) float64 {
+	total := 0.0
+	for i, grade := range gains {
+		if i >= k {
+			break
+		}
+		if grade <= 0 {
+			continue
+		}
+		total += (math.Pow(2, grade) - 1) / math.Log2(float64(i+2))
+	}
+	return total
+}
+
+func countRelevant(ranked []string, grades map[string]float64) int {
+	count := 0
+	for _, uri := range ranked {
+		if grades[uri] > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func truncate(ranked []string, k int) []string {
+	if k < 0 {
+		k = 0
+	}
+	if len(ranked) > k {
+		return ranked[:k]
+	}
+	return ranked
+}
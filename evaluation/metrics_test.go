@@ -0,0 +1,56 @@
+package evaluation_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/coveo/go-coveo/evaluation"
+)
+
+func TestMetrics(t *testing.T) {
+	grades := map[string]float64{"a": 3, "b": 2, "c": 0, "d": 1}
+	ranked := []string{"c", "a", "x", "d"}
+
+	m := evaluation.Compute(ranked, grades, 3)
+
+	expectedDCG := 7 / math.Log2(3)
+	idealDCG := 7/math.Log2(2) + 3/math.Log2(3) + 1/math.Log2(4)
+	assertFloat(t, "ndcg", expectedDCG/idealDCG, m.NDCG)
+	assertFloat(t, "mrr", 0.5, m.MRR)
+	assertFloat(t, "precision", 1.0/3, m.Precision)
+	assertFloat(t, "recall", 1.0/3, m.Recall)
+}
+
+func TestReadJudgments(t *testing.T) {
+	input := "query,uri,grade\nfoo,http://a,3\nbar,http://b,1\nfoo,http://c,0\n"
+
+	l, err := evaluation.ReadJudgments(strings.NewReader(input), ',')
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	queries := l.Queries()
+	if len(queries) != 2 || queries[0] != "foo" || queries[1] != "bar" {
+		t.Fatalf("unexpected queries.  expected %v, actual %v", []string{"foo", "bar"}, queries)
+	}
+	if grade := l.Grades("foo")["http://a"]; grade != 3 {
+		t.Fatalf("unexpected grade.  expected %v, actual %v", 3, grade)
+	}
+}
+
+func TestReadJudgmentsInvalidGrade(t *testing.T) {
+	input := "# graded by hand\nquery,uri,grade\n# foo\nfoo,http://a,high\n"
+
+	_, err := evaluation.ReadJudgments(strings.NewReader(input), ',')
+	expected := `record 2: invalid grade "high"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error.  expected %v, actual %v", expected, err)
+	}
+}
+
+func assertFloat(t *testing.T, name string, expected, actual float64) {
+	if math.Abs(expected-actual) > 1e-9 {
+		t.Errorf("unexpected %s.  expected %v, actual %v", name, expected, actual)
+	}
+}
@@ -0,0 +1,146 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report is the result of running a judgment list in a pipeline
+type Report struct {
+	Pipeline string        `json:"pipeline,omitempty"`
+	K        int           `json:"k"`
+	Mean     Metrics       `json:"mean"`
+	Queries  []QueryReport `json:"queries"`
+}
+
+// QueryReport holds the documents returned for a single query and their metrics
+type QueryReport struct {
+	Query   string   `json:"query"`
+	Results []string `json:"results"`
+	Metrics Metrics  `json:"metrics"`
+}
+
+// Comparison is the per-query difference between a baseline and a candidate
+// report. Deltas are computed as candidate minus baseline.
+type Comparison struct {
+	Baseline  *Report     `json:"baseline"`
+	Candidate *Report     `json:"candidate"`
+	Delta     Metrics     `json:"delta"`
+	Queries   []QueryDiff `json:"queries"`
+}
+
+// QueryDiff is the difference of the metrics of a single query between two
+// reports
+type QueryDiff struct {
+	Query     string  `json:"query"`
+	Baseline  Metrics `json:"baseline"`
+	Candidate Metrics `json:"candidate"`
+	Delta     Metrics `json:"delta"`
+}
+
+// Compare returns the differences between two reports. Queries missing from
+// the candidate report are compared with zero metrics.
+func Compare(baseline, candidate *Report) *Comparison {
+	candidateQueries := map[string]Metrics{}
+	for _, q := range candidate.Queries {
+		candidateQueries[q.Query] = q.Metrics
+	}
+
+	c := &Comparison{
+		Baseline:  baseline,
+		Candidate: candidate,
+		Delta:     delta(baseline.Mean, candidate.Mean),
+	}
+	for _, q := range baseline.Queries {
+		candidateMetrics := candidateQueries[q.Query]
+		c.Queries = append(c.Queries, QueryDiff{
+			Query:     q.Query,
+			Baseline:  q.Metrics,
+			Candidate: candidateMetrics,
+			Delta:     delta(q.Metrics, candidateMetrics),
+		})
+	}
+	return c
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	return writeJSON(w, r)
+}
+
+// WriteMarkdown writes the report as a Markdown table
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Relevance report: %s\n\n", pipelineName(r.Pipeline))
+	fmt.Fprintf(&b, "| Query | NDCG@%d | MRR | P@%d | R@%d |\n", r.K, r.K, r.K)
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, q := range r.Queries {
+		writeMetricsRow(&b, q.Query, q.Metrics)
+	}
+	writeMetricsRow(&b, "**Mean**", r.Mean)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the comparison as indented JSON
+func (c *Comparison) WriteJSON(w io.Writer) error {
+	return writeJSON(w, c)
+}
+
+// WriteMarkdown writes the comparison as a Markdown table, with the metrics of
+// the candidate and their delta from the baseline.
+func (c *Comparison) WriteMarkdown(w io.Writer) error {
+	k := c.Baseline.K
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Relevance comparison: %s vs %s\n\n",
+		pipelineName(c.Baseline.Pipeline), pipelineName(c.Candidate.Pipeline))
+	fmt.Fprintf(&b, "| Query | NDCG@%d | MRR | P@%d | R@%d |\n", k, k, k)
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, q := range c.Queries {
+		writeDiffRow(&b, q.Query, q.Candidate, q.Delta)
+	}
+	writeDiffRow(&b, "**Mean**", c.Candidate.Mean, c.Delta)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeMetricsRow(b *strings.Builder, query string, m Metrics) {
+	fmt.Fprintf(b, "| %s | %.4f | %.4f | %.4f | %.4f |\n",
+		escapeMarkdown(query), m.NDCG, m.MRR, m.Precision, m.Recall)
+}
+
+func writeDiffRow(b *strings.Builder, query string, m Metrics, d Metrics) {
+	fmt.Fprintf(b, "| %s | %.4f (%+.4f) | %.4f (%+.4f) | %.4f (%+.4f) | %.4f (%+.4f) |\n",
+		escapeMarkdown(query),
+		m.NDCG, d.NDCG, m.MRR, d.MRR, m.Precision, d.Precision, m.Recall, d.Recall)
+}
+
+func delta(baseline, candidate Metrics) Metrics {
+	return Metrics{
+		NDCG:      candidate.NDCG - baseline.NDCG,
+		MRR:       candidate.MRR - baseline.MRR,
+		Precision: candidate.Precision - baseline.Precision,
+		Recall:    candidate.Recall - baseline.Recall,
+	}
+}
+
+func pipelineName(pipeline string) string {
+	if len(pipeline) == 0 {
+		return "default pipeline"
+	}
+	return pipeline
+}
+
+func escapeMarkdown(s string) string {
+	return strings.Replace(s, "|", "\\|", -1)
+}
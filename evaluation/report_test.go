@@ -0,0 +1,89 @@
+package evaluation_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coveo/go-coveo/evaluation"
+)
+
+func report() *evaluation.Report {
+	return &evaluation.Report{
+		Pipeline: "mypipeline",
+		K:        5,
+		Mean:     evaluation.Metrics{NDCG: 0.75, MRR: 0.5, Precision: 0.25, Recall: 1},
+		Queries: []evaluation.QueryReport{
+			{Query: "foo|bar", Results: []string{"http://a"}, Metrics: evaluation.Metrics{NDCG: 0.75, MRR: 0.5, Precision: 0.25, Recall: 1}},
+		},
+	}
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := report().WriteJSON(buf); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	decoded := &evaluation.Report{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if !reflect.DeepEqual(decoded, report()) {
+		t.Errorf("unexpected report.  expected %+v, actual %+v", report(), decoded)
+	}
+}
+
+func TestReportWriteMarkdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := report().WriteMarkdown(buf); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expected := "# Relevance report: mypipeline\n\n" +
+		"| Query | NDCG@5 | MRR | P@5 | R@5 |\n" +
+		"|---|---|---|---|---|\n" +
+		"| foo\\|bar | 0.7500 | 0.5000 | 0.2500 | 1.0000 |\n" +
+		"| **Mean** | 0.7500 | 0.5000 | 0.2500 | 1.0000 |\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected markdown.  expected %q, actual %q", expected, buf.String())
+	}
+}
+
+func TestComparisonWriters(t *testing.T) {
+	baseline := report()
+	baseline.Pipeline = ""
+	candidate := report()
+	candidate.Queries[0].Metrics.MRR = 1
+	candidate.Mean.MRR = 1
+	c := evaluation.Compare(baseline, candidate)
+
+	buf := &bytes.Buffer{}
+	if err := c.WriteMarkdown(buf); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	markdown := buf.String()
+	for _, expected := range []string{
+		"# Relevance comparison: default pipeline vs mypipeline\n",
+		"| foo\\|bar | 0.7500 (+0.0000) | 1.0000 (+0.5000) | 0.2500 (+0.0000) | 1.0000 (+0.0000) |\n",
+		"| **Mean** | 0.7500 (+0.0000) | 1.0000 (+0.5000) |",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("unexpected markdown.  expected to contain %q, actual %q", expected, markdown)
+		}
+	}
+
+	buf.Reset()
+	if err := c.WriteJSON(buf); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	decoded := &evaluation.Comparison{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if !reflect.DeepEqual(decoded, c) {
+		t.Errorf("unexpected comparison.  expected %+v, actual %+v", c, decoded)
+	}
+}
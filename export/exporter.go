@@ -0,0 +1,250 @@
+// Package export dumps every result matching a query to JSON Lines or CSV.
+//
+// The search API only lets you page through the first results of a query, so
+// the exporter sorts the results on a slice field, usually @rowid or a date
+// field, and restarts the query after the last value exported each time a
+// page is read.
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/coveo/go-coveo/search"
+)
+
+const (
+	// DefaultSliceField is the field used to slice the results when none is given
+	DefaultSliceField = "rowid"
+	// DefaultPageSize is the number of results requested per query when none
+	// is given
+	DefaultPageSize = 1000
+	// MaxResultWindow is the position after which the search API does not
+	// return results
+	MaxResultWindow = 5000
+)
+
+// Progress reports the state of an export after each page
+type Progress struct {
+	// Exported is the number of results written so far, including the ones
+	// written before resuming from a checkpoint
+	Exported int64
+	// Total is the number of results matching the query when the export started
+	Total int
+}
+
+// Checkpoint is the state saved after each page, used to resume an export
+type Checkpoint struct {
+	LastValue interface{} `json:"lastValue"`
+	Seen      []string    `json:"seen"`
+	Exported  int64       `json:"exported"`
+	// Total is the number of results matching the query when the export
+	// started, the queries of a resumed export only match the remaining ones
+	Total int `json:"total"`
+}
+
+// Exporter pages through every result matching an advanced query expression
+type Exporter struct {
+	// Client is the search client used to run the queries
+	Client search.Client
+	// AQ is the advanced query expression selecting the results to export
+	AQ string
+	// Fields are the raw fields to export, the slice field is always retrieved
+	Fields []string
+	// SliceField is the sortable field used to slice the results, without the
+	// @ prefix. Its values should be unique or mostly unique.
+	SliceField string
+	// SliceOnDate must be set when the slice field is a date field
+	SliceOnDate bool
+	// PageSize is the number of results requested per query
+	PageSize int
+	// Progress is called after each page is written
+	Progress func(Progress)
+	// CheckpointPath is the file where the checkpoint is saved after each page.
+	// When the file exists, the export resumes from it.
+	CheckpointPath string
+}
+
+// Export writes every result to w. When a checkpoint is found, only the
+// results that come after it are written.
+func (e *Exporter) Export(w Writer) error {
+	if e.Client == nil {
+		return errors.New("You need a search client")
+	}
+
+	sliceField := e.SliceField
+	if len(sliceField) == 0 {
+		sliceField = DefaultSliceField
+	}
+	pageSize := e.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	checkpoint, err := e.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, uri := range checkpoint.Seen {
+		seen[uri] = true
+	}
+
+	skip := 0
+	for {
+		if skip != 0 && skip+pageSize > MaxResultWindow {
+			return fmt.Errorf("more than %d results have the value %v for the slice field %s, use a slice field with more unique values",
+				skip, checkpoint.LastValue, sliceField)
+		}
+
+		query := search.Query{
+			AQ:              e.sliceExpression(sliceField, checkpoint.LastValue),
+			SortCriteria:    "@" + sliceField + " ascending",
+			NumberOfResults: pageSize,
+			FirstResult:     skip,
+			FieldsToInclude: append([]string{sliceField}, e.Fields...),
+		}
+
+		response, err := e.Client.Query(query)
+		if err != nil {
+			return err
+		}
+		if checkpoint.Total == 0 {
+			// The results sharing the last value are matched again, the
+			// results before it are not
+			checkpoint.Total = int(checkpoint.Exported) - len(checkpoint.Seen) + response.TotalCount
+		}
+
+		written := 0
+		for _, result := range response.Results {
+			value := result.Raw[sliceField]
+			if value == nil {
+				return fmt.Errorf("result %s has no value for the slice field %s", result.URI, sliceField)
+			}
+			if checkpoint.LastValue != nil {
+				if less(value, checkpoint.LastValue) {
+					continue
+				}
+				if equal(value, checkpoint.LastValue) && seen[result.URI] {
+					continue
+				}
+			}
+
+			if err := w.Write(result); err != nil {
+				return err
+			}
+			written++
+			checkpoint.Exported++
+
+			if checkpoint.LastValue == nil || !equal(value, checkpoint.LastValue) {
+				checkpoint.LastValue = value
+				checkpoint.Seen = nil
+				seen = map[string]bool{}
+			}
+			checkpoint.Seen = append(checkpoint.Seen, result.URI)
+			seen[result.URI] = true
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := e.saveCheckpoint(checkpoint); err != nil {
+			return err
+		}
+		if e.Progress != nil {
+			e.Progress(Progress{Exported: checkpoint.Exported, Total: checkpoint.Total})
+		}
+
+		if len(response.Results) < pageSize {
+			return nil
+		}
+
+		// A full page where everything was already exported means more results
+		// share the last value than fit in a page, skip over them.
+		if written == 0 {
+			skip += len(response.Results)
+		} else {
+			skip = 0
+		}
+	}
+}
+
+func (e *Exporter) sliceExpression(sliceField string, lastValue interface{}) string {
+	if lastValue == nil {
+		return e.AQ
+	}
+
+	expression := fmt.Sprintf("@%s>=%s", sliceField, e.formatValue(lastValue))
+	if len(e.AQ) == 0 {
+		return expression
+	}
+	return fmt.Sprintf("(%s) %s", e.AQ, expression)
+}
+
+func (e *Exporter) formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if e.SliceOnDate {
+			return time.Unix(0, int64(v)*int64(time.Millisecond)).UTC().Format("2006/01/02@15:04:05")
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return strconv.Quote(fmt.Sprint(v))
+	}
+}
+
+func (e *Exporter) loadCheckpoint() (*Checkpoint, error) {
+	checkpoint := &Checkpoint{}
+	if len(e.CheckpointPath) == 0 {
+		return checkpoint, nil
+	}
+
+	content, err := ioutil.ReadFile(e.CheckpointPath)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", e.CheckpointPath, err)
+	}
+	return checkpoint, nil
+}
+
+func (e *Exporter) saveCheckpoint(checkpoint *Checkpoint) error {
+	if len(e.CheckpointPath) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp := e.CheckpointPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.CheckpointPath)
+}
+
+func less(a, b interface{}) bool {
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			return x < y
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+func equal(a, b interface{}) bool {
+	return !less(a, b) && !less(b, a)
+}
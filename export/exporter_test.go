@@ -0,0 +1,156 @@
+package export_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/coveo/go-coveo/export"
+	"github.com/coveo/go-coveo/search"
+)
+
+var sliceExpression = regexp.MustCompile(`@rowid>=([0-9]+)`)
+
+// fakeSearch returns its results sorted on @rowid, filtered with the
+// @rowid>=N expression of the query, and fails after failAfter queries
+type fakeSearch struct {
+	search.Client
+	results   []search.Result
+	queries   []search.Query
+	failAfter int
+}
+
+func (s *fakeSearch) Query(q search.Query) (*search.Response, error) {
+	s.queries = append(s.queries, q)
+	if s.failAfter != 0 && len(s.queries) > s.failAfter {
+		return nil, errors.New("the search API is unavailable")
+	}
+
+	min := -1.0
+	if match := sliceExpression.FindStringSubmatch(q.AQ); match != nil {
+		min, _ = strconv.ParseFloat(match[1], 64)
+	}
+	matching := []search.Result{}
+	for _, r := range s.results {
+		if r.Raw["rowid"].(float64) >= min {
+			matching = append(matching, r)
+		}
+	}
+
+	response := &search.Response{TotalCount: len(matching)}
+	for i := q.FirstResult; i < len(matching) && i < q.FirstResult+q.NumberOfResults; i++ {
+		response.Results = append(response.Results, matching[i])
+	}
+	return response, nil
+}
+
+func results(rowids ...int) []search.Result {
+	results := []search.Result{}
+	for i, rowid := range rowids {
+		results = append(results, search.Result{
+			URI: fmt.Sprintf("file://%d", i),
+			Raw: map[string]interface{}{"rowid": float64(rowid)},
+		})
+	}
+	return results
+}
+
+// recordingWriter records the URIs written
+type recordingWriter struct {
+	uris []string
+}
+
+func (w *recordingWriter) Write(r search.Result) error {
+	w.uris = append(w.uris, r.URI)
+	return nil
+}
+
+func (w *recordingWriter) Flush() error {
+	return nil
+}
+
+func TestExportPages(t *testing.T) {
+	// Results 2 to 4 share the same value
+	client := &fakeSearch{results: results(1, 2, 2, 2, 3, 4, 5)}
+	w := &recordingWriter{}
+	progress := []export.Progress{}
+	e := &export.Exporter{
+		Client:   client,
+		PageSize: 2,
+		Progress: func(p export.Progress) { progress = append(progress, p) },
+	}
+	if err := e.Export(w); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if len(w.uris) != 7 {
+		t.Fatalf("unexpected results.  expected %v, actual %v", 7, w.uris)
+	}
+	for i, uri := range w.uris {
+		if expected := fmt.Sprintf("file://%d", i); uri != expected {
+			t.Errorf("unexpected result.  expected %v, actual %v", expected, uri)
+		}
+	}
+	last := progress[len(progress)-1]
+	if last.Exported != 7 || last.Total != 7 {
+		t.Errorf("unexpected progress.  expected 7 of 7, actual %+v", last)
+	}
+}
+
+func TestExportResumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+
+	// The first run fails after 2 pages
+	client := &fakeSearch{results: results(1, 2, 3, 3, 4, 5, 6), failAfter: 2}
+	first := &recordingWriter{}
+	e := &export.Exporter{Client: client, PageSize: 2, CheckpointPath: checkpoint}
+	if err := e.Export(first); err == nil {
+		t.Fatalf("expected an error when the search API fails")
+	}
+	if len(first.uris) != 3 {
+		t.Fatalf("unexpected results.  expected %v, actual %v", 3, first.uris)
+	}
+
+	client.failAfter = 0
+	second := &recordingWriter{}
+	progress := []export.Progress{}
+	e.Progress = func(p export.Progress) { progress = append(progress, p) }
+	if err := e.Export(second); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expected := []string{"file://3", "file://4", "file://5", "file://6"}
+	if fmt.Sprint(second.uris) != fmt.Sprint(expected) {
+		t.Errorf("unexpected resumed results.  expected %v, actual %v", expected, second.uris)
+	}
+	// The total is the one of the export, not of the resumed queries
+	for _, p := range progress {
+		if p.Total != 7 || p.Exported > int64(p.Total) {
+			t.Errorf("unexpected progress.  expected at most 7 of 7, actual %+v", p)
+		}
+	}
+}
+
+func TestExportResultWindow(t *testing.T) {
+	rowids := make([]int, export.MaxResultWindow+10)
+	client := &fakeSearch{results: results(rowids...)}
+	e := &export.Exporter{Client: client, PageSize: 1000}
+	if err := e.Export(&recordingWriter{}); err == nil {
+		t.Fatalf("expected an error when more results share a value than the search API returns")
+	}
+	for _, q := range client.queries {
+		if q.FirstResult+q.NumberOfResults > export.MaxResultWindow {
+			t.Errorf("unexpected query past the result window.  expected at most %v, actual %v", export.MaxResultWindow, q.FirstResult+q.NumberOfResults)
+		}
+	}
+}
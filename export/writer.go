@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/coveo/go-coveo/search"
+)
+
+// Writer writes exported results in a given format
+type Writer interface {
+	// Write writes a single result
+	Write(r search.Result) error
+	// Flush writes any buffered data to the underlying writer
+	Flush() error
+}
+
+// NewJSONLWriter returns a Writer that writes one JSON object per line. Only
+// the given raw fields are written, or every raw field if fields is empty.
+func NewJSONLWriter(w io.Writer, fields []string) Writer {
+	buffered := bufio.NewWriter(w)
+	return &jsonlWriter{
+		buf:     buffered,
+		encoder: json.NewEncoder(buffered),
+		fields:  fields,
+	}
+}
+
+type jsonlWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+	fields  []string
+}
+
+func (w *jsonlWriter) Write(r search.Result) error {
+	if len(w.fields) == 0 {
+		return w.encoder.Encode(r.Raw)
+	}
+
+	row := make(map[string]interface{}, len(w.fields))
+	for _, field := range w.fields {
+		if value, ok := r.Raw[field]; ok {
+			row[field] = value
+		}
+	}
+	return w.encoder.Encode(row)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// NewCSVWriter returns a Writer that writes the given raw fields as CSV
+// columns. Multi-value fields are joined with a semicolon. The header line is
+// only written if header is true, so that a resumed export can append to an
+// existing file.
+func NewCSVWriter(w io.Writer, fields []string, header bool) Writer {
+	return &csvWriter{
+		writer:      csv.NewWriter(w),
+		fields:      fields,
+		writeHeader: header,
+	}
+}
+
+type csvWriter struct {
+	writer      *csv.Writer
+	fields      []string
+	writeHeader bool
+}
+
+func (w *csvWriter) Write(r search.Result) error {
+	if w.writeHeader {
+		if err := w.writer.Write(w.fields); err != nil {
+			return err
+		}
+		w.writeHeader = false
+	}
+
+	row := make([]string, len(w.fields))
+	for i, field := range w.fields {
+		row[i] = formatValue(r.Raw[field])
+	}
+	return w.writer.Write(row)
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			values[i] = formatValue(item)
+		}
+		return strings.Join(values, ";")
+	default:
+		return fmt.Sprint(v)
+	}
+}
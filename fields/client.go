@@ -0,0 +1,241 @@
+// Package fields manages the fields of an organization index through the
+// platform API, so they exist before documents using them are pushed.
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/coveo/go-coveo/auth"
+	"github.com/coveo/go-coveo/endpoint"
+	"github.com/coveo/go-coveo/internal/httpapi"
+)
+
+// maxBatchFields is the number of fields sent per batch request
+const maxBatchFields = 50
+
+// Client is the fields client to list and modify the fields of an organization
+type Client interface {
+	List() ([]Field, error)
+	Create(fields []Field) error
+	Update(fields []Field) error
+	Delete(names []string) error
+}
+
+// Config is used to configure a new client
+type Config struct {
+	// Endpoint is the platform API endpoint, resolved from the region and
+	// environment if empty
+	Endpoint string
+	// The Coveo organization ID
+	OrganizationID string
+	// APIKey is the key used to manage the fields, it needs the privilege to
+	// edit fields
+	APIKey string
+	// TokenSource provides the key of every request, APIKey is used if nil
+	TokenSource auth.TokenSource
+	// Region is the region of the organization, used when Endpoint is empty
+	Region endpoint.Region
+	// Environment is the environment of the organization, used when Endpoint
+	// is empty
+	Environment endpoint.Environment
+}
+
+// NewClient initializes a new fields client with the config param
+func NewClient(c Config) (Client, error) {
+	if len(c.Endpoint) == 0 {
+		resolved, err := endpoint.Resolve(endpoint.Platform, c.Environment, c.Region)
+		if err != nil {
+			return nil, err
+		}
+		c.Endpoint = resolved
+	}
+
+	if c.TokenSource == nil {
+		c.TokenSource = auth.StaticToken(c.APIKey)
+	}
+
+	return &client{
+		endpoint:       c.Endpoint,
+		organizationid: c.OrganizationID,
+		httpClient:     auth.NewHTTPClient(c.TokenSource),
+	}, nil
+}
+
+type client struct {
+	httpClient     *http.Client
+	endpoint       string
+	organizationid string
+}
+
+type fieldsPage struct {
+	Items      []Field `json:"items"`
+	TotalPages int     `json:"totalPages"`
+}
+
+// List returns every field of the organization
+func (c *client) List() ([]Field, error) {
+	fields := []Field{}
+	for page := 0; ; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("perPage", "100")
+
+		req, err := http.NewRequest("GET", c.fieldsEndpoint("page/fields")+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := c.sendRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		result := fieldsPage{}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+		fields = append(fields, result.Items...)
+
+		if page+1 >= result.TotalPages {
+			return fields, nil
+		}
+	}
+}
+
+// Create creates the fields
+func (c *client) Create(fields []Field) error {
+	if err := checkNames(fields); err != nil {
+		return err
+	}
+
+	items := make([]interface{}, len(fields))
+	for i, f := range fields {
+		items[i] = f
+	}
+	return c.sendBatches("POST", "fields/batch/create", items)
+}
+
+// Update updates the attributes of the fields modeled by Field, the fields
+// being identified by their name. The attributes it does not model are kept
+// as they are.
+func (c *client) Update(fields []Field) error {
+	if err := checkNames(fields); err != nil {
+		return err
+	}
+
+	items := make([]interface{}, len(fields))
+	for i, f := range fields {
+		merged, err := c.mergeRaw(f)
+		if err != nil {
+			return err
+		}
+		items[i] = merged
+	}
+	return c.sendBatches("PUT", "fields/batch/update", items)
+}
+
+// Delete deletes the named fields
+func (c *client) Delete(names []string) error {
+	for start := 0; start < len(names); start += maxBatchFields {
+		end := start + maxBatchFields
+		if end > len(names) {
+			end = len(names)
+		}
+
+		query := url.Values{}
+		for _, name := range names[start:end] {
+			query.Add("fields", name)
+		}
+
+		req, err := http.NewRequest("DELETE", c.fieldsEndpoint("fields/batch/delete")+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		if _, err := c.sendRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRaw gets the field as raw JSON and replaces the attributes modeled by
+// Field. Field only models some of the attributes of a field, round-tripping
+// the raw JSON keeps the other ones untouched.
+func (c *client) mergeRaw(f Field) (map[string]json.RawMessage, error) {
+	req, err := http.NewRequest("GET", c.fieldsEndpoint("fields/"+url.PathEscape(f.Name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	marshalledField, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	modeled := map[string]json.RawMessage{}
+	if err := json.Unmarshal(marshalledField, &modeled); err != nil {
+		return nil, err
+	}
+	// System is reported by the platform, it cannot be updated
+	delete(modeled, "system")
+	for key, value := range modeled {
+		raw[key] = value
+	}
+	return raw, nil
+}
+
+func checkNames(fields []Field) error {
+	for _, f := range fields {
+		if len(f.Name) == 0 {
+			return errors.New("You need to provide a field name")
+		}
+	}
+	return nil
+}
+
+// sendBatches sends the items in batches of at most maxBatchFields
+func (c *client) sendBatches(method, path string, items []interface{}) error {
+	for start := 0; start < len(items); start += maxBatchFields {
+		end := start + maxBatchFields
+		if end > len(items) {
+			end = len(items)
+		}
+
+		marshalledItems, err := json.Marshal(items[start:end])
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(method, c.fieldsEndpoint(path), bytes.NewReader(marshalledItems))
+		if err != nil {
+			return err
+		}
+		if _, err := c.sendRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) fieldsEndpoint(path string) string {
+	return fmt.Sprintf("%s%s/indexes/%s", c.endpoint, url.PathEscape(c.organizationid), path)
+}
+
+func (c *client) sendRequest(req *http.Request) ([]byte, error) {
+	req.Header.Add("Accept", "application/json")
+	return httpapi.Send(c.httpClient, req)
+}
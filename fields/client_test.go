@@ -0,0 +1,98 @@
+package fields_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coveo/go-coveo/fields"
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestClientReturnsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errorCode": "INVALID_FIELD", "message": "The field is invalid"}`))
+	}))
+	defer server.Close()
+
+	c, err := fields.NewClient(fields.Config{Endpoint: server.URL + "/", OrganizationID: "myorg"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	err = c.Create([]fields.Field{{Name: "title", Type: fields.TypeString}})
+	apiErr, ok := err.(*pushapi.APIError)
+	if !ok {
+		t.Fatalf("unexpected error.  expected a *pushapi.APIError, actual %#v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.ErrorCode != "INVALID_FIELD" {
+		t.Errorf("unexpected error.  expected %v INVALID_FIELD, actual %+v", http.StatusBadRequest, apiErr)
+	}
+}
+
+func TestClientSendsBatches(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/myorg/indexes/fields/batch/delete" {
+			t.Errorf("unexpected request.  expected %v, actual %v %v", "DELETE /myorg/indexes/fields/batch/delete", r.Method, r.URL.Path)
+		}
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c, err := fields.NewClient(fields.Config{Endpoint: server.URL + "/", OrganizationID: "myorg"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	names := make([]string, 120)
+	for i := range names {
+		names[i] = "field"
+	}
+	if err := c.Delete(names); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if requests != 3 {
+		t.Errorf("unexpected requests.  expected %v, actual %v", 3, requests)
+	}
+}
+
+func TestUpdateKeepsUnknownAttributes(t *testing.T) {
+	var updated []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /myorg/indexes/fields/title":
+			w.Write([]byte(`{"name": "title", "type": "STRING", "facet": false, "sortByFacetValue": true, "keyValueField": "other"}`))
+		case "PUT /myorg/indexes/fields/batch/update":
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+				t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c, err := fields.NewClient(fields.Config{Endpoint: server.URL + "/", OrganizationID: "myorg"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if err := c.Update([]fields.Field{{Name: "title", Type: fields.TypeString, Facet: fields.Bool(true)}}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(updated) != 1 {
+		t.Fatalf("unexpected update.  expected %v field, actual %v", 1, updated)
+	}
+	if updated[0]["facet"] != true {
+		t.Errorf("unexpected facet.  expected %v, actual %v", true, updated[0]["facet"])
+	}
+	if updated[0]["sortByFacetValue"] != true || updated[0]["keyValueField"] != "other" {
+		t.Errorf("expected the unknown attributes to be kept, actual %v", updated[0])
+	}
+}
@@ -0,0 +1,71 @@
+package fields
+
+// Type is the type of the values of a field
+type Type string
+
+const (
+	// TypeString is a field holding text
+	TypeString Type = "STRING"
+	// TypeLong is a field holding 32 bits integers
+	TypeLong Type = "LONG"
+	// TypeLong64 is a field holding 64 bits integers
+	TypeLong64 Type = "LONG_64"
+	// TypeDouble is a field holding decimal numbers
+	TypeDouble Type = "DOUBLE"
+	// TypeDate is a field holding dates
+	TypeDate Type = "DATE"
+)
+
+// Field is the definition of a field of the index. The attributes left nil are
+// not sent: the platform applies its defaults when the field is created and
+// keeps their current values when it is updated. The fields returned by List
+// have every attribute the platform reports.
+type Field struct {
+	// Name is the name of the field, lowercase without the @ prefix
+	Name string `json:"name"`
+	// Type is the type of the values of the field, required to create it
+	Type Type `json:"type,omitempty"`
+	// Description describes the field
+	Description *string `json:"description,omitempty"`
+	// Facet makes the field usable in facets and group by requests
+	Facet *bool `json:"facet,omitempty"`
+	// MultiValueFacet makes every value of a multi-value field a facet value,
+	// the values being separated by semicolons
+	MultiValueFacet *bool `json:"multiValueFacet,omitempty"`
+	// Sort makes the field usable as a sort criteria
+	Sort *bool `json:"sort,omitempty"`
+	// IncludeInQuery makes the field usable in query expressions
+	IncludeInQuery *bool `json:"includeInQuery,omitempty"`
+	// IncludeInResults returns the field in the raw values of the results
+	IncludeInResults *bool `json:"includeInResults,omitempty"`
+	// MergeWithLexicon makes the field searchable with free text queries
+	MergeWithLexicon *bool `json:"mergeWithLexicon,omitempty"`
+	// Ranking uses the free text matches of the field for the ranking
+	Ranking *bool `json:"ranking,omitempty"`
+	// Stemming matches the words of the field with their stem
+	Stemming *bool `json:"stemming,omitempty"`
+	// UseCacheForSort keeps the field in memory to speed up sorting
+	UseCacheForSort *bool `json:"useCacheForSort,omitempty"`
+	// UseCacheForComputedFacet keeps the field in memory to speed up
+	// computed fields
+	UseCacheForComputedFacet *bool `json:"useCacheForComputedFacet,omitempty"`
+	// UseCacheForNestedQuery keeps the field in memory to speed up nested
+	// queries
+	UseCacheForNestedQuery *bool `json:"useCacheForNestedQuery,omitempty"`
+	// UseCacheForNumericQuery keeps the field in memory to speed up numeric
+	// queries
+	UseCacheForNumericQuery *bool `json:"useCacheForNumericQuery,omitempty"`
+	// System is set by the platform for the fields it manages, they cannot be
+	// modified
+	System bool `json:"system,omitempty"`
+}
+
+// Bool returns a pointer to the value, to set the attributes of a Field
+func Bool(v bool) *bool {
+	return &v
+}
+
+// String returns a pointer to the value, to set the attributes of a Field
+func String(v string) *string {
+	return &v
+}
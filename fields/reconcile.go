@@ -0,0 +1,127 @@
+package fields
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Plan lists the changes needed for the fields of an organization to match
+// the desired definitions
+type Plan struct {
+	Create []Field  `json:"create"`
+	Update []Field  `json:"update"`
+	Delete []string `json:"delete"`
+}
+
+// Empty returns true if the plan has no changes
+func (p *Plan) Empty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// ReconcileOptions controls how fields are reconciled
+type ReconcileOptions struct {
+	// DeleteUnknown deletes the fields of the organization that are not
+	// desired, system fields are never deleted
+	DeleteUnknown bool
+	// DryRun computes the plan without applying it
+	DryRun bool
+}
+
+// Diff returns the plan to go from the existing fields to the desired ones.
+// Field names are compared without case, and only the attributes set in the
+// desired fields are compared.
+func Diff(existing, desired []Field, o ReconcileOptions) *Plan {
+	current := map[string]Field{}
+	for _, f := range existing {
+		current[strings.ToLower(f.Name)] = f
+	}
+
+	plan := &Plan{}
+	wanted := map[string]bool{}
+	for _, f := range desired {
+		name := strings.ToLower(f.Name)
+		wanted[name] = true
+
+		existingField, ok := current[name]
+		if !ok {
+			plan.Create = append(plan.Create, f)
+			continue
+		}
+
+		// The names only differ by case, and System is only reported by the
+		// platform, neither is part of the definition
+		f.Name = existingField.Name
+		f.System = existingField.System
+		if changes(f, existingField) {
+			plan.Update = append(plan.Update, f)
+		}
+	}
+
+	if o.DeleteUnknown {
+		for name, f := range current {
+			if !wanted[name] && !f.System {
+				plan.Delete = append(plan.Delete, f.Name)
+			}
+		}
+		sort.Strings(plan.Delete)
+	}
+
+	return plan
+}
+
+// changes returns true if an attribute set in the desired field differs from
+// the existing field, an attribute missing from the existing field being zero
+func changes(desired, existing Field) bool {
+	if len(desired.Type) != 0 && desired.Type != existing.Type {
+		return true
+	}
+
+	d := reflect.ValueOf(desired)
+	e := reflect.ValueOf(existing)
+	for i := 0; i < d.NumField(); i++ {
+		attribute := d.Field(i)
+		if attribute.Kind() != reflect.Ptr || attribute.IsNil() {
+			continue
+		}
+		current := reflect.Zero(attribute.Type().Elem())
+		if !e.Field(i).IsNil() {
+			current = e.Field(i).Elem()
+		}
+		if attribute.Elem().Interface() != current.Interface() {
+			return true
+		}
+	}
+	return false
+}
+
+// Reconcile compares the desired fields to the fields of the organization and
+// applies the differences, unless DryRun is set. It returns the plan computed.
+func Reconcile(c Client, desired []Field, o ReconcileOptions) (*Plan, error) {
+	existing, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := Diff(existing, desired, o)
+	if o.DryRun {
+		return plan, nil
+	}
+
+	if len(plan.Create) != 0 {
+		if err := c.Create(plan.Create); err != nil {
+			return plan, err
+		}
+	}
+	if len(plan.Update) != 0 {
+		if err := c.Update(plan.Update); err != nil {
+			return plan, err
+		}
+	}
+	if len(plan.Delete) != 0 {
+		if err := c.Delete(plan.Delete); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,156 @@
+package fields_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/coveo/go-coveo/fields"
+)
+
+func TestDiff(t *testing.T) {
+	title := fields.Field{Name: "title", Type: fields.TypeString, Facet: fields.Bool(true)}
+	size := fields.Field{Name: "size", Type: fields.TypeLong}
+	system := fields.Field{Name: "urihash", Type: fields.TypeString, System: true}
+
+	tests := []struct {
+		name     string
+		existing []fields.Field
+		desired  []fields.Field
+		options  fields.ReconcileOptions
+		expected fields.Plan
+	}{
+		{
+			name:     "create",
+			existing: []fields.Field{title},
+			desired:  []fields.Field{title, size},
+			expected: fields.Plan{Create: []fields.Field{size}},
+		},
+		{
+			name:     "unchanged",
+			existing: []fields.Field{title, size},
+			desired:  []fields.Field{title, size},
+		},
+		{
+			name:     "names compared without case",
+			existing: []fields.Field{title},
+			desired:  []fields.Field{{Name: "Title", Type: fields.TypeString, Facet: fields.Bool(true)}},
+		},
+		{
+			name:     "update",
+			existing: []fields.Field{title},
+			desired:  []fields.Field{{Name: "Title", Type: fields.TypeString, Sort: fields.Bool(true)}},
+			expected: fields.Plan{Update: []fields.Field{{Name: "title", Type: fields.TypeString, Sort: fields.Bool(true)}}},
+		},
+		{
+			name:     "system fields are not part of the definition",
+			existing: []fields.Field{system},
+			desired:  []fields.Field{{Name: "urihash", Type: fields.TypeString}},
+		},
+		{
+			name:     "unknown fields are kept",
+			existing: []fields.Field{title, size},
+			desired:  []fields.Field{title},
+		},
+		{
+			name:     "delete unknown fields except system fields",
+			existing: []fields.Field{title, size, system},
+			desired:  []fields.Field{title},
+			options:  fields.ReconcileOptions{DeleteUnknown: true},
+			expected: fields.Plan{Delete: []string{"size"}},
+		},
+	}
+
+	for _, test := range tests {
+		plan := fields.Diff(test.existing, test.desired, test.options)
+		if !reflect.DeepEqual(*plan, test.expected) {
+			t.Errorf("unexpected plan for %s.  expected %+v, actual %+v", test.name, test.expected, *plan)
+		}
+	}
+}
+
+// platformFields keeps the fields as raw JSON and merges the updates into
+// them, like the platform does
+type platformFields struct {
+	fields.Client
+	raw map[string]map[string]interface{}
+}
+
+func (p *platformFields) List() ([]fields.Field, error) {
+	list := []fields.Field{}
+	for _, raw := range p.raw {
+		marshalled, _ := json.Marshal(raw)
+		f := fields.Field{}
+		if err := json.Unmarshal(marshalled, &f); err != nil {
+			return nil, err
+		}
+		list = append(list, f)
+	}
+	return list, nil
+}
+
+func (p *platformFields) Create(list []fields.Field) error {
+	for _, f := range list {
+		// The platform defaults
+		p.raw[f.Name] = map[string]interface{}{"includeInQuery": true, "includeInResults": true}
+	}
+	return p.Update(list)
+}
+
+func (p *platformFields) Update(list []fields.Field) error {
+	for _, f := range list {
+		marshalled, _ := json.Marshal(f)
+		changes := map[string]interface{}{}
+		if err := json.Unmarshal(marshalled, &changes); err != nil {
+			return err
+		}
+		for key, value := range changes {
+			p.raw[f.Name][key] = value
+		}
+	}
+	return nil
+}
+
+func TestReconcileSettles(t *testing.T) {
+	platform := &platformFields{raw: map[string]map[string]interface{}{
+		"title": {
+			"name":             "title",
+			"type":             "STRING",
+			"description":      "Set in the console",
+			"facet":            false,
+			"includeInQuery":   true,
+			"includeInResults": true,
+		},
+	}}
+	desired := []fields.Field{
+		{Name: "title", Type: fields.TypeString, Facet: fields.Bool(true)},
+		{Name: "size", Type: fields.TypeLong, Sort: fields.Bool(true)},
+	}
+
+	plan, err := fields.Reconcile(platform, desired, fields.ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(plan.Create) != 1 || len(plan.Update) != 1 {
+		t.Fatalf("unexpected plan.  expected 1 creation and 1 update, actual %+v", plan)
+	}
+
+	plan, err = fields.Reconcile(platform, desired, fields.ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if !plan.Empty() {
+		t.Errorf("unexpected plan.  expected an empty plan, actual %+v", plan)
+	}
+
+	// The attributes that are not desired keep their value
+	for _, name := range []string{"title", "size"} {
+		raw := platform.raw[name]
+		if raw["includeInQuery"] != true || raw["includeInResults"] != true {
+			t.Errorf("unexpected attributes for %s.  expected the field to stay in queries and results, actual %v", name, raw)
+		}
+	}
+	if platform.raw["title"]["description"] != "Set in the console" || platform.raw["title"]["facet"] != true {
+		t.Errorf("unexpected title.  expected the description kept and the facet set, actual %v", platform.raw["title"])
+	}
+}
@@ -0,0 +1,199 @@
+// Package filesystem indexes the files of a directory tree in a push source.
+//
+// Every regular file becomes a document identified by its file URI, with its
+// name as title, its modification date and its content as compressed binary
+// data. Synchronized through an incremental.Engine, only the files that
+// changed since the last run are read and pushed, and the documents of the
+// files removed from the tree are deleted.
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/coveo/go-coveo/incremental"
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// DefaultMaxFileSize is the size in bytes above which files are left out when
+// no MaxFileSize is given. Base64 makes the content a third larger, a larger
+// file may not fit in a batch of pushapi.DefaultMaxBatchSize bytes.
+const DefaultMaxFileSize = pushapi.DefaultMaxBatchSize/4*3 - 1024*1024
+
+// ErrSkip can be returned by Connector.Map to leave a file out of the source
+var ErrSkip = errors.New("skip this file")
+
+// ErrFileTooLarge is reported to Connector.OnError for the files larger than
+// the maximum file size
+var ErrFileTooLarge = errors.New("the file is larger than the maximum file size")
+
+// Connector maps the files of a directory tree to documents
+type Connector struct {
+	// Root is the directory to index
+	Root string
+	// Include are the glob patterns of the files to index, every file if
+	// empty. A pattern without a slash matches the file name, a pattern with
+	// a slash matches the path relative to Root, with slash separators.
+	Include []string
+	// Exclude are the glob patterns of the files and directories to leave
+	// out, matched like Include. Excluding a directory excludes its content.
+	Exclude []string
+	// MaxFileSize is the size in bytes above which files are left out,
+	// DefaultMaxFileSize if zero and no limit if negative. The content of the
+	// largest file must fit in a batch of the pusher.
+	MaxFileSize int64
+	// Compression is the compression of the binary data, zlib if empty
+	Compression pushapi.CompressionType
+	// Map is called for every document before it is pushed, to add metadata
+	// or to leave the file out by returning ErrSkip
+	Map func(path string, info os.FileInfo, d *pushapi.Document) error
+	// OnError is called for the files and directories that cannot be read
+	// and for the files too large, with ErrFileTooLarge. They are left out
+	// and the walk goes on, so their documents are deleted like the ones of
+	// removed files.
+	OnError func(path string, err error)
+}
+
+// Walk calls fn with the document of every file of the tree to index, in
+// lexical order. It stops at the first error.
+func (c *Connector) Walk(fn func(pushapi.Document) error) error {
+	return c.walk(func(p string, info os.FileInfo) error {
+		d, err := c.document(p, info)
+		if err == ErrSkip {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(*d)
+	})
+}
+
+// Sync synchronizes the source of the engine with the files of the tree. The
+// files whose size and modification date did not change since the last
+// synchronization are not read, and Map is not called for them. If the tree
+// cannot be walked entirely, nothing is deleted.
+func (c *Connector) Sync(e *incremental.Engine) (incremental.Stats, error) {
+	return e.SyncItems(func(add func(incremental.Item) error) error {
+		return c.walk(func(p string, info os.FileInfo) error {
+			return add(incremental.Item{
+				DocumentID: FileURI(p),
+				Version:    fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()),
+				Build: func() (*pushapi.Document, error) {
+					d, err := c.document(p, info)
+					if err == ErrSkip {
+						return nil, nil
+					}
+					return d, err
+				},
+			})
+		})
+	})
+}
+
+// walk calls fn with the absolute path of every file of the tree to index, in
+// lexical order
+func (c *Connector) walk(fn func(p string, info os.FileInfo) error) error {
+	if len(c.Root) == 0 {
+		return errors.New("You need a root directory")
+	}
+	root, err := filepath.Abs(c.Root)
+	if err != nil {
+		return err
+	}
+
+	maxFileSize := c.MaxFileSize
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if p == root {
+			return err
+		}
+		if err != nil {
+			// The entry, or the content of the directory, cannot be read
+			c.report(p, err)
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if match(c.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || match(c.Exclude, rel) {
+			return nil
+		}
+		if len(c.Include) != 0 && !match(c.Include, rel) {
+			return nil
+		}
+		if maxFileSize > 0 && info.Size() > maxFileSize {
+			c.report(p, ErrFileTooLarge)
+			return nil
+		}
+		return fn(p, info)
+	})
+}
+
+func (c *Connector) document(p string, info os.FileInfo) (*pushapi.Document, error) {
+	d := &pushapi.Document{
+		DocumentID:   FileURI(p),
+		Title:        info.Name(),
+		Date:         info.ModTime(),
+		ModifiedDate: info.ModTime(),
+	}
+	if err := d.SetBinaryDataFromFile(p, c.Compression); err != nil {
+		c.report(p, err)
+		return nil, ErrSkip
+	}
+
+	if c.Map != nil {
+		if err := c.Map(p, info, d); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (c *Connector) report(p string, err error) {
+	if c.OnError != nil {
+		c.OnError(p, err)
+	}
+}
+
+// FileURI returns the file URI of the absolute path, used as document ID
+func FileURI(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		// Windows paths start with the drive letter
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// match returns true if the relative path matches one of the patterns
+func match(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package filesystem_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/filesystem"
+	"github.com/coveo/go-coveo/incremental"
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/pushapi/pushapitest"
+)
+
+func tree(t *testing.T, files ...string) string {
+	root, err := ioutil.TempDir("", "filesystem")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content of "+f), 0644); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	return root
+}
+
+func TestWalk(t *testing.T) {
+	root := tree(t, "a.txt", "b.pdf", "docs/c.txt", "docs/draft/d.txt", ".git/config")
+	defer os.RemoveAll(root)
+
+	c := &filesystem.Connector{
+		Root:    root,
+		Include: []string{"*.txt", "*.pdf"},
+		Exclude: []string{".git", "docs/draft"},
+	}
+	documents := []pushapi.Document{}
+	err := c.Walk(func(d pushapi.Document) error {
+		documents = append(documents, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	ids := []string{}
+	for _, d := range documents {
+		ids = append(ids, strings.TrimPrefix(d.DocumentID, filesystem.FileURI(root)))
+	}
+	expected := []string{"/a.txt", "/b.pdf", "/docs/c.txt"}
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected documents.  expected %v, actual %v", expected, ids)
+	}
+
+	d := documents[0]
+	if d.Title != "a.txt" || d.FileExtension != ".txt" || d.ModifiedDate.IsZero() {
+		t.Errorf("unexpected document.  expected title, extension and date, actual %+v", d)
+	}
+	if len(d.CompressedBinaryData) == 0 || d.CompressionType != pushapi.CompressionZlib {
+		t.Errorf("unexpected binary data.  expected zlib data, actual %q %v", d.CompressedBinaryData, d.CompressionType)
+	}
+}
+
+func TestSyncDeletesRemovedFiles(t *testing.T) {
+	root := tree(t, "a.txt", "b.txt")
+	defer os.RemoveAll(root)
+
+	c := &filesystem.Connector{Root: root}
+	engine := &incremental.Engine{
+		Client:   &pushapitest.BatchClient{},
+		SourceID: "mysource",
+		Store:    incremental.NewMemoryStore(),
+	}
+
+	stats, err := c.Sync(engine)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats.New != 2 {
+		t.Fatalf("unexpected stats.  expected %v new, actual %+v", 2, stats)
+	}
+
+	if err := os.Remove(filepath.Join(root, "b.txt")); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	stats, err = c.Sync(engine)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats.Unchanged != 1 || stats.Deleted != 1 {
+		t.Errorf("unexpected stats.  expected 1 unchanged and 1 deleted, actual %+v", stats)
+	}
+}
+
+func TestSyncReadsChangedFilesOnly(t *testing.T) {
+	root := tree(t, "a.txt", "b.txt")
+	defer os.RemoveAll(root)
+
+	mapped := []string{}
+	c := &filesystem.Connector{
+		Root: root,
+		Map: func(p string, info os.FileInfo, d *pushapi.Document) error {
+			mapped = append(mapped, info.Name())
+			return nil
+		},
+	}
+	engine := &incremental.Engine{
+		Client:   &pushapitest.BatchClient{},
+		SourceID: "mysource",
+		Store:    incremental.NewMemoryStore(),
+	}
+	if _, err := c.Sync(engine); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	b := filepath.Join(root, "b.txt")
+	if err := ioutil.WriteFile(b, []byte("new content of b.txt"), 0644); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := os.Chtimes(b, later, later); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	mapped = nil
+	stats, err := c.Sync(engine)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats.Unchanged != 1 || stats.Changed != 1 {
+		t.Errorf("unexpected stats.  expected 1 unchanged and 1 changed, actual %+v", stats)
+	}
+	if len(mapped) != 1 || mapped[0] != "b.txt" {
+		t.Errorf("unexpected files read.  expected %v, actual %v", []string{"b.txt"}, mapped)
+	}
+}
+
+func TestSyncSkipsTooLargeFiles(t *testing.T) {
+	root := tree(t, "a.txt")
+	defer os.RemoveAll(root)
+
+	// The sparse file is never read
+	large := filepath.Join(root, "large.bin")
+	if err := ioutil.WriteFile(large, nil, 0644); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := os.Truncate(large, filesystem.DefaultMaxFileSize+1); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	skipped := map[string]error{}
+	c := &filesystem.Connector{
+		Root:    root,
+		OnError: func(p string, err error) { skipped[filepath.Base(p)] = err },
+	}
+	client := &pushapitest.BatchClient{}
+	engine := &incremental.Engine{Client: client, SourceID: "mysource", Store: incremental.NewMemoryStore()}
+
+	stats, err := c.Sync(engine)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats.New != 1 || len(client.Documents()) != 1 {
+		t.Errorf("unexpected stats.  expected only a.txt to be pushed, actual %+v", stats)
+	}
+	if len(skipped) != 1 || skipped["large.bin"] != filesystem.ErrFileTooLarge {
+		t.Errorf("unexpected skipped files.  expected large.bin too large, actual %v", skipped)
+	}
+
+	// The default limit leaves room for base64 in a batch
+	if int64(filesystem.DefaultMaxFileSize)/3*4 >= pushapi.DefaultMaxBatchSize {
+		t.Errorf("unexpected default limit.  expected %v bytes encoded to fit in %v bytes", filesystem.DefaultMaxFileSize, pushapi.DefaultMaxBatchSize)
+	}
+}
+
+func TestSyncSkipsUnreadableEntries(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("the permissions do not apply to root")
+	}
+
+	root := tree(t, "a.txt", "b.txt", "private/c.txt")
+	defer os.RemoveAll(root)
+	for _, p := range []string{"b.txt", "private"} {
+		if err := os.Chmod(filepath.Join(root, p), 0); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+		defer os.Chmod(filepath.Join(root, p), 0755)
+	}
+
+	skipped := []string{}
+	c := &filesystem.Connector{
+		Root:    root,
+		OnError: func(p string, err error) { skipped = append(skipped, filepath.Base(p)) },
+	}
+	engine := &incremental.Engine{Client: &pushapitest.BatchClient{}, SourceID: "mysource", Store: incremental.NewMemoryStore()}
+
+	stats, err := c.Sync(engine)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats.New != 1 {
+		t.Errorf("unexpected stats.  expected only a.txt to be pushed, actual %+v", stats)
+	}
+	if strings.Join(skipped, ",") != "b.txt,private" {
+		t.Errorf("unexpected skipped entries.  expected %v, actual %v", "b.txt,private", skipped)
+	}
+}
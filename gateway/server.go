@@ -0,0 +1,384 @@
+// Package gateway is an HTTP server receiving documents and identities from
+// other services and forwarding them to the Push API. It holds the API key,
+// authenticates its callers with their own tokens and validates the payloads
+// before accepting them.
+//
+// Every accepted request is persisted in a durable queue as a batch, so a
+// request acknowledged with 202 Accepted survives a restart of the gateway.
+// The queued batches of a source, or of a security provider, are merged up to
+// the size limit of the Push API, then sent in order and retried until the
+// Push API accepts them.
+//
+// The endpoints are:
+//
+//	POST   /sources/{sourceID}/documents       add or update documents
+//	DELETE /sources/{sourceID}/documents       delete documents
+//	PUT    /providers/{providerID}/identities  add, update or delete identities
+//	DELETE /providers/{providerID}/identities  delete identities
+//	GET    /queue                              queue statistics
+//	GET    /health                             health check, not authenticated
+//
+// The documents and identities are sent as a JSON array, or as a single JSON
+// object.
+package gateway
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/queue"
+)
+
+// DefaultMaxBodySize is the maximum size in bytes of a request body
+const DefaultMaxBodySize = 100 * 1024 * 1024
+
+// Config is used to configure a new server
+type Config struct {
+	// Client is the client forwarding the operations to the Push API
+	Client pushapi.Client
+	// QueueDir is the directory of the durable queue of the operations
+	QueueDir string
+	// QueueOptions are the retry options of the queue. The batches are always
+	// merged.
+	QueueOptions queue.Options
+	// Tokens are the bearer tokens accepted from the callers
+	Tokens []string
+	// Sources are the source IDs callers can push to, any if empty
+	Sources []string
+	// Providers are the security provider IDs callers can push to, any if
+	// empty
+	Providers []string
+	// MaxBodySize is the maximum size in bytes of a request body,
+	// DefaultMaxBodySize if zero
+	MaxBodySize int64
+}
+
+// Server is the HTTP handler of the gateway
+type Server struct {
+	queue       *queue.Queue
+	tokens      [][]byte
+	sources     map[string]bool
+	providers   map[string]bool
+	maxBodySize int64
+}
+
+// NewServer opens the queue of the gateway and returns its handler. Call
+// Close once done.
+func NewServer(c Config) (*Server, error) {
+	if c.Client == nil {
+		return nil, errors.New("You need a client")
+	}
+	if len(c.QueueDir) == 0 {
+		return nil, errors.New("You need a queue directory")
+	}
+	if len(c.Tokens) == 0 {
+		return nil, errors.New("You need at least one token")
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = DefaultMaxBodySize
+	}
+
+	c.QueueOptions.MergeBatches = true
+	q, err := queue.Open(c.QueueDir, c.Client, c.QueueOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		queue:       q,
+		sources:     toSet(c.Sources),
+		providers:   toSet(c.Providers),
+		maxBodySize: c.MaxBodySize,
+	}
+	for _, token := range c.Tokens {
+		s.tokens = append(s.tokens, []byte(token))
+	}
+	return s, nil
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// Stats returns the statistics of the queue
+func (s *Server) Stats() queue.Stats {
+	return s.queue.Stats()
+}
+
+// Drain blocks until every accepted operation was sent or dropped
+func (s *Server) Drain() error {
+	return s.queue.Drain()
+}
+
+// Close stops forwarding the operations, the ones not sent yet are kept in
+// the queue directory and sent by the next server
+func (s *Server) Close() error {
+	return s.queue.Close()
+}
+
+// ServeHTTP routes the request to its endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/health" {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	if !s.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid or missing token")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "queue" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, s.queue.Stats())
+	case len(parts) == 3 && parts[0] == "sources" && parts[2] == "documents":
+		if !allowed(s.sources, parts[1]) {
+			writeError(w, http.StatusForbidden, "unknown source "+parts[1])
+			return
+		}
+		switch r.Method {
+		case "POST":
+			s.pushDocuments(w, r, parts[1])
+		case "DELETE":
+			s.deleteDocuments(w, r, parts[1])
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(parts) == 3 && parts[0] == "providers" && parts[2] == "identities":
+		if !allowed(s.providers, parts[1]) {
+			writeError(w, http.StatusForbidden, "unknown security provider "+parts[1])
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			s.pushIdentities(w, r, parts[1])
+		case "DELETE":
+			s.deleteIdentities(w, r, parts[1])
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(header, "Bearer "))
+
+	ok := false
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare(token, t) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func allowed(set map[string]bool, id string) bool {
+	return len(id) != 0 && (set == nil || set[id])
+}
+
+// acceptedResponse is the response of the accepted requests
+type acceptedResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// invalidItem is an error of an item of a request
+type invalidItem struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (s *Server) pushDocuments(w http.ResponseWriter, r *http.Request, sourceID string) {
+	documents := []pushapi.Document{}
+	if !s.decode(w, r, &documents) {
+		return
+	}
+
+	invalid := []invalidItem{}
+	for i, d := range documents {
+		if len(d.DocumentID) == 0 {
+			invalid = append(invalid, invalidItem{Index: i, Field: "documentId", Message: "the document ID is required"})
+			continue
+		}
+		if err := pushapi.Validate(d); err != nil {
+			invalid = append(invalid, validationItems(i, d.DocumentID, err)...)
+		}
+	}
+	if !s.checkItems(w, len(documents), invalid) {
+		return
+	}
+
+	s.enqueue(w, len(documents), s.queue.BatchPush(pushapi.Batch{AddOrUpdate: documents}, sourceID))
+}
+
+func (s *Server) deleteDocuments(w http.ResponseWriter, r *http.Request, sourceID string) {
+	deleted := []pushapi.DeletedDocument{}
+	if documentID := r.URL.Query().Get("documentId"); len(documentID) != 0 {
+		deleted = append(deleted, pushapi.DeletedDocument{
+			DocumentID:     documentID,
+			DeleteChildren: r.URL.Query().Get("deleteChildren") == "true",
+		})
+	} else if !s.decode(w, r, &deleted) {
+		return
+	}
+
+	invalid := []invalidItem{}
+	for i, d := range deleted {
+		if err := d.Validate(); err != nil {
+			invalid = append(invalid, validationItems(i, d.DocumentID, err)...)
+		}
+	}
+	if !s.checkItems(w, len(deleted), invalid) {
+		return
+	}
+
+	s.enqueue(w, len(deleted), s.queue.BatchPush(pushapi.Batch{Delete: deleted}, sourceID))
+}
+
+func (s *Server) pushIdentities(w http.ResponseWriter, r *http.Request, providerID string) {
+	identities := []pushapi.Identity{}
+	if !s.decode(w, r, &identities) {
+		return
+	}
+
+	invalid := []invalidItem{}
+	batch := pushapi.IdentityBatch{}
+	for i, identity := range identities {
+		if err := identity.Validate(); err != nil {
+			invalid = append(invalid, validationItems(i, identity.Identity.Name, err)...)
+			continue
+		}
+		if len(identity.Mappings) != 0 {
+			batch.Mappings = append(batch.Mappings, identity)
+		} else {
+			batch.Members = append(batch.Members, identity)
+		}
+	}
+	if !s.checkItems(w, len(identities), invalid) {
+		return
+	}
+
+	s.enqueue(w, len(identities), s.queue.PushIdentityBatch(batch, providerID, pushapi.BatchOptions{}))
+}
+
+func (s *Server) deleteIdentities(w http.ResponseWriter, r *http.Request, providerID string) {
+	identities := []pushapi.Identity{}
+	if !s.decode(w, r, &identities) {
+		return
+	}
+
+	invalid := []invalidItem{}
+	batch := pushapi.IdentityBatch{}
+	for i, identity := range identities {
+		if len(identity.Identity.Name) == 0 {
+			invalid = append(invalid, invalidItem{Index: i, Field: "identity.name", Message: "the identity name is required"})
+			continue
+		}
+		batch.Deleted = append(batch.Deleted, pushapi.Identity{Identity: identity.Identity})
+	}
+	if !s.checkItems(w, len(identities), invalid) {
+		return
+	}
+
+	s.enqueue(w, len(identities), s.queue.PushIdentityBatch(batch, providerID, pushapi.BatchOptions{}))
+}
+
+// decode reads the body as a JSON array into v, a pointer to a slice, or as a
+// single JSON object appended to it. It writes the error response and
+// returns false if the body is invalid.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return false
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) != 0 && body[0] == '{' {
+		body = append(append([]byte{'['}, body...), ']')
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// checkItems writes the error response and returns false if the request has
+// no items or invalid ones
+func (s *Server) checkItems(w http.ResponseWriter, count int, invalid []invalidItem) bool {
+	if count == 0 {
+		writeError(w, http.StatusBadRequest, "the request has no items")
+		return false
+	}
+	if len(invalid) != 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message": "the request has invalid items",
+			"errors":  invalid,
+		})
+		return false
+	}
+	return true
+}
+
+// enqueue writes the response of a request whose operation was enqueued
+func (s *Server) enqueue(w http.ResponseWriter, count int, err error) {
+	if err == queue.ErrClosed {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, acceptedResponse{Accepted: count})
+}
+
+func validationItems(index int, id string, err error) []invalidItem {
+	errs, ok := err.(pushapi.ValidationErrors)
+	if !ok {
+		return []invalidItem{{Index: index, ID: id, Message: err.Error()}}
+	}
+
+	items := []invalidItem{}
+	for _, e := range errs {
+		items = append(items, invalidItem{Index: index, ID: id, Field: e.Field, Message: e.Message})
+	}
+	return items
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,212 @@
+package gateway_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/gateway"
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/queue"
+)
+
+// recordingClient records the batches instead of sending them
+type recordingClient struct {
+	pushapi.Client
+	mu              sync.Mutex
+	batches         []pushapi.Batch
+	identityBatches []pushapi.IdentityBatch
+	// unavailable, if set, is waited for by BatchPush, which then fails once
+	unavailable chan struct{}
+	failed      bool
+}
+
+func (c *recordingClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	if c.unavailable != nil {
+		<-c.unavailable
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unavailable != nil && !c.failed {
+		c.failed = true
+		return &pushapi.APIError{StatusCode: http.StatusServiceUnavailable}
+	}
+	c.batches = append(c.batches, b)
+	return nil
+}
+
+func (c *recordingClient) PushIdentityBatch(b pushapi.IdentityBatch, providerID string, o pushapi.BatchOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.identityBatches = append(c.identityBatches, b)
+	return nil
+}
+
+func newServer(t *testing.T, client pushapi.Client) (*gateway.Server, *httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	s, err := gateway.NewServer(gateway.Config{
+		Client:   client,
+		QueueDir: dir,
+		QueueOptions: queue.Options{
+			RetryDelay: time.Millisecond,
+		},
+		Tokens:  []string{"secret"},
+		Sources: []string{"mysource"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	server := httptest.NewServer(s)
+	return s, server, func() {
+		server.Close()
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func request(t *testing.T, method, url, token, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestGatewayForwardsDocuments(t *testing.T) {
+	// The Push API is unavailable until every request is queued, the requests
+	// are then sent as a single batch
+	client := &recordingClient{unavailable: make(chan struct{})}
+	s, server, done := newServer(t, client)
+	defer done()
+
+	resp := request(t, "POST", server.URL+"/sources/mysource/documents", "secret",
+		`[{"documentId": "file://a", "title": "A"}, {"documentId": "file://b", "title": "B"}]`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status.  expected %v, actual %v", http.StatusAccepted, resp.StatusCode)
+	}
+	resp = request(t, "DELETE", server.URL+"/sources/mysource/documents?documentId=file://c", "secret", "")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status.  expected %v, actual %v", http.StatusAccepted, resp.StatusCode)
+	}
+	resp = request(t, "PUT", server.URL+"/providers/myprovider/identities", "secret",
+		`{"identity": {"name": "a@example.com", "type": "USER"}}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status.  expected %v, actual %v", http.StatusAccepted, resp.StatusCode)
+	}
+
+	close(client.unavailable)
+	if err := s.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(client.batches) != 1 || len(client.batches[0].AddOrUpdate) != 2 || client.batches[0].Delete[0].DocumentID != "file://c" {
+		t.Errorf("unexpected batches.  expected 2 documents and 1 deletion, actual %+v", client.batches)
+	}
+	if len(client.identityBatches) != 1 || client.identityBatches[0].Members[0].Identity.Name != "a@example.com" {
+		t.Errorf("unexpected identity batches.  expected a@example.com, actual %+v", client.identityBatches)
+	}
+}
+
+func TestGatewayRejectsInvalidRequests(t *testing.T) {
+	client := &recordingClient{}
+	_, server, done := newServer(t, client)
+	defer done()
+
+	tests := []struct {
+		method, path, token, body string
+		status                    int
+	}{
+		{"GET", "/health", "", "", http.StatusOK},
+		{"GET", "/queue", "", "", http.StatusUnauthorized},
+		{"GET", "/queue", "wrong", "", http.StatusUnauthorized},
+		{"GET", "/queue", "secret", "", http.StatusOK},
+		{"POST", "/sources/othersource/documents", "secret", `{"documentId": "file://a"}`, http.StatusForbidden},
+		{"POST", "/sources/mysource/documents", "secret", `{"title": "no ID"}`, http.StatusBadRequest},
+		{"POST", "/sources/mysource/documents", "secret", `not json`, http.StatusBadRequest},
+		{"POST", "/sources/mysource/documents", "secret", `[]`, http.StatusBadRequest},
+		{"DELETE", "/sources/mysource/documents?documentId=relative", "secret", "", http.StatusBadRequest},
+		{"GET", "/sources/mysource/documents", "secret", "", http.StatusMethodNotAllowed},
+		{"GET", "/unknown", "secret", "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		resp := request(t, test.method, server.URL+test.path, test.token, test.body)
+		if resp.StatusCode != test.status {
+			t.Errorf("unexpected status for %s %s.  expected %v, actual %v", test.method, test.path, test.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestGatewayRejectsInvalidDeletions(t *testing.T) {
+	client := &recordingClient{}
+	s, server, done := newServer(t, client)
+	defer done()
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/sources/mysource/documents",
+		strings.NewReader(`[{"documentId": "file://a"}, {"documentId": "not a URI"}]`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status.  expected %v, actual %v", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	body := struct {
+		Errors []struct {
+			Index int    `json:"index"`
+			Field string `json:"field"`
+		} `json:"errors"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Index != 1 || body.Errors[0].Field != "documentId" {
+		t.Errorf("unexpected errors.  expected %v on item %v, actual %+v", "documentId", 1, body.Errors)
+	}
+
+	if err := s.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(client.batches) != 0 {
+		t.Errorf("unexpected batches.  expected none, actual %+v", client.batches)
+	}
+}
+
+func TestGatewayQueueStatus(t *testing.T) {
+	_, server, done := newServer(t, &recordingClient{})
+	defer done()
+
+	req, _ := http.NewRequest("GET", server.URL+"/queue", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer resp.Body.Close()
+
+	stats := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if _, ok := stats["depth"]; !ok {
+		t.Errorf("unexpected queue status.  expected a depth, actual %v", stats)
+	}
+}
@@ -0,0 +1,235 @@
+// Package incremental pushes only the documents that changed since the last
+// synchronization of a source.
+//
+// The engine keeps, in a StateStore, the hash of the content last pushed for
+// every document and the ordering ID it was pushed with. Documents with the
+// same hash are skipped and documents that are not part of the input anymore
+// are deleted. With SyncItems, documents whose version did not change are not
+// even built. An entry is only saved once the Push API accepted the batch
+// holding its document, so a synchronization stopped by a crash resumes where
+// it stopped when it is run again.
+package incremental
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// Stats are the totals of a synchronization
+type Stats struct {
+	// New is the number of documents pushed for the first time
+	New int
+	// Changed is the number of documents pushed again because they changed
+	Changed int
+	// Unchanged is the number of documents skipped
+	Unchanged int
+	// Deleted is the number of documents deleted because they were not part
+	// of the input
+	Deleted int
+	// Push holds the totals of the batches sent
+	Push pushapi.PusherStats
+}
+
+// Engine synchronizes a source with a set of documents
+type Engine struct {
+	// Client is the client used to push the documents
+	Client pushapi.Client
+	// SourceID is the source to synchronize
+	SourceID string
+	// Store keeps the state of the pushed documents
+	Store StateStore
+	// PusherConfig configures the batches, its SourceID and OnBatch are set by
+	// the engine
+	PusherConfig pushapi.PusherConfig
+}
+
+// Sync pushes every new or changed document received on the channel until it
+// is closed, then deletes the known documents that were not received.
+func (e *Engine) Sync(documents <-chan pushapi.Document) (Stats, error) {
+	stats, err := e.SyncFunc(func(add func(pushapi.Document) error) error {
+		for d := range documents {
+			if err := add(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Do not leave the sender blocked on the channel
+		go func() {
+			for range documents {
+			}
+		}()
+	}
+	return stats, err
+}
+
+// SyncFunc pushes every new or changed document given to add by produce,
+// then deletes the known documents that were not given. A document given
+// twice is an error. If produce returns an error, the synchronization stops
+// and no document is deleted, since the documents not given yet may still
+// exist.
+func (e *Engine) SyncFunc(produce func(add func(pushapi.Document) error) error) (Stats, error) {
+	return e.SyncItems(func(add func(Item) error) error {
+		return produce(func(d pushapi.Document) error {
+			return add(Item{
+				DocumentID: d.DocumentID,
+				Build:      func() (*pushapi.Document, error) { return &d, nil },
+			})
+		})
+	})
+}
+
+// Item is a document given to SyncItems, built only if its version changed
+// since it was last pushed
+type Item struct {
+	// DocumentID is the ID of the document
+	DocumentID string
+	// Version identifies the state of what the document is built from, like
+	// the size and modification date of a file. The document is always built
+	// if it is empty.
+	Version string
+	// Build returns the document, or nil to leave it out of the source
+	Build func() (*pushapi.Document, error)
+}
+
+// SyncItems is SyncFunc for documents expensive to build: an item whose
+// version is the one stored is counted unchanged without being built.
+func (e *Engine) SyncItems(produce func(add func(Item) error) error) (Stats, error) {
+	stats := Stats{}
+	if e.Client == nil || e.Store == nil {
+		return stats, errors.New("You need a client and a state store")
+	}
+
+	var mu sync.Mutex
+	pending := map[string]Entry{}
+	var storeErr error
+
+	config := e.PusherConfig
+	config.SourceID = e.SourceID
+	config.OnBatch = func(r pushapi.BatchResult) {
+		if r.Err != nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range r.Batch.AddOrUpdate {
+			entry := pending[d.DocumentID]
+			entry.OrderingID = r.OrderingID
+			if err := e.Store.Put(d.DocumentID, entry); err != nil && storeErr == nil {
+				storeErr = err
+			}
+			delete(pending, d.DocumentID)
+		}
+		for _, d := range r.Batch.Delete {
+			if err := e.Store.Delete(d.DocumentID); err != nil && storeErr == nil {
+				storeErr = err
+			}
+		}
+	}
+	pusher := pushapi.NewPusher(e.Client, config)
+
+	seen := map[string]bool{}
+	err := func() error {
+		err := produce(func(item Item) error {
+			if len(item.DocumentID) == 0 {
+				return errors.New("You need to provide a documentID")
+			}
+			if seen[item.DocumentID] {
+				return fmt.Errorf("the document %s was given twice", item.DocumentID)
+			}
+
+			previous, known, err := e.Store.Get(item.DocumentID)
+			if err != nil {
+				return err
+			}
+			if known && len(item.Version) != 0 && previous.Version == item.Version {
+				seen[item.DocumentID] = true
+				stats.Unchanged++
+				return nil
+			}
+
+			d, err := item.Build()
+			if err != nil {
+				return err
+			}
+			if d == nil {
+				return nil
+			}
+			if d.DocumentID != item.DocumentID {
+				return fmt.Errorf("the document %s was built with the ID %s", item.DocumentID, d.DocumentID)
+			}
+			seen[item.DocumentID] = true
+
+			hash, err := Hash(*d)
+			if err != nil {
+				return err
+			}
+			if known && previous.Hash == hash {
+				stats.Unchanged++
+				if previous.Version == item.Version {
+					return nil
+				}
+				previous.Version = item.Version
+				return e.Store.Put(item.DocumentID, previous)
+			}
+			if known {
+				stats.Changed++
+			} else {
+				stats.New++
+			}
+
+			mu.Lock()
+			pending[d.DocumentID] = Entry{Hash: hash, Version: item.Version}
+			mu.Unlock()
+
+			return pusher.Add(*d)
+		})
+		if err != nil {
+			return err
+		}
+
+		ids, err := e.Store.DocumentIDs()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			if err := pusher.Delete(pushapi.DeletedDocument{DocumentID: id}); err != nil {
+				return err
+			}
+			stats.Deleted++
+		}
+		return nil
+	}()
+
+	var closeErr error
+	stats.Push, closeErr = pusher.Close()
+	if err != nil {
+		return stats, err
+	}
+	if closeErr != nil {
+		return stats, closeErr
+	}
+	return stats, storeErr
+}
+
+// Hash returns the hash of the content of the document, its ordering ID
+// excluded
+func Hash(d pushapi.Document) (string, error) {
+	marshalled, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(marshalled)
+	return hex.EncodeToString(sum[:]), nil
+}
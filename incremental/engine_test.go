@@ -0,0 +1,168 @@
+package incremental_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coveo/go-coveo/incremental"
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/pushapi/pushapitest"
+)
+
+func runSync(t *testing.T, engine *incremental.Engine, documents ...pushapi.Document) incremental.Stats {
+	ch := make(chan pushapi.Document, len(documents))
+	for _, d := range documents {
+		ch <- d
+	}
+	close(ch)
+
+	stats, err := engine.Sync(ch)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return stats
+}
+
+func TestEngineSkipsUnchangedAndDeletesMissing(t *testing.T) {
+	engine := &incremental.Engine{
+		Client:   &pushapitest.BatchClient{},
+		SourceID: "mysource",
+		Store:    incremental.NewMemoryStore(),
+	}
+
+	a := pushapi.Document{DocumentID: "file://a", Data: "a"}
+	b := pushapi.Document{DocumentID: "file://b", Data: "b"}
+	stats := runSync(t, engine, a, b)
+	if stats.New != 2 {
+		t.Fatalf("unexpected new documents.  expected %v, actual %+v", 2, stats)
+	}
+
+	a.Data = "changed"
+	stats = runSync(t, engine, a)
+	if stats.Changed != 1 || stats.Unchanged != 0 || stats.Deleted != 1 {
+		t.Fatalf("unexpected stats.  expected 1 changed and 1 deleted, actual %+v", stats)
+	}
+
+	stats = runSync(t, engine, a)
+	if stats.Unchanged != 1 || stats.Push.Batches != 0 {
+		t.Fatalf("unexpected stats.  expected 1 unchanged and no batch, actual %+v", stats)
+	}
+}
+
+func TestEngineStoresOrderingIDs(t *testing.T) {
+	client := &pushapitest.BatchClient{}
+	store := incremental.NewMemoryStore()
+	engine := &incremental.Engine{Client: client, SourceID: "mysource", Store: store}
+
+	runSync(t, engine, pushapi.Document{DocumentID: "file://a", Data: "a"})
+	batches := client.Batches()
+	if len(batches) != 1 || batches[0].OrderingID == 0 {
+		t.Fatalf("unexpected batches.  expected 1 batch with an ordering ID, actual %+v", batches)
+	}
+
+	entry, _, err := store.Get("file://a")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if entry.OrderingID != batches[0].OrderingID {
+		t.Errorf("unexpected ordering ID.  expected %v, actual %v", batches[0].OrderingID, entry.OrderingID)
+	}
+}
+
+func TestSyncFuncErrorDeletesNothing(t *testing.T) {
+	client := &pushapitest.BatchClient{}
+	engine := &incremental.Engine{
+		Client:   client,
+		SourceID: "mysource",
+		Store:    incremental.NewMemoryStore(),
+	}
+	runSync(t, engine, pushapi.Document{DocumentID: "file://a"}, pushapi.Document{DocumentID: "file://b"})
+
+	_, err := engine.SyncFunc(func(add func(pushapi.Document) error) error {
+		if err := add(pushapi.Document{DocumentID: "file://a"}); err != nil {
+			return err
+		}
+		return errors.New("walk failed")
+	})
+	if err == nil {
+		t.Fatalf("expected the error of the producer")
+	}
+
+	ids, _ := engine.Store.DocumentIDs()
+	if len(ids) != 2 {
+		t.Errorf("unexpected known documents.  expected %v, actual %v", 2, ids)
+	}
+	for _, b := range client.Batches() {
+		if len(b.Delete) != 0 {
+			t.Errorf("unexpected deletion.  expected none, actual %v", b.Delete)
+		}
+	}
+}
+
+func TestSyncFuncRejectsDuplicates(t *testing.T) {
+	engine := &incremental.Engine{
+		Client:   &pushapitest.BatchClient{},
+		SourceID: "mysource",
+		Store:    incremental.NewMemoryStore(),
+	}
+
+	_, err := engine.SyncFunc(func(add func(pushapi.Document) error) error {
+		if err := add(pushapi.Document{DocumentID: "file://a", Data: "a"}); err != nil {
+			return err
+		}
+		return add(pushapi.Document{DocumentID: "file://a", Data: "b"})
+	})
+	if err == nil {
+		t.Fatalf("expected an error for the duplicate document")
+	}
+
+	// The entry saved is the one of the document pushed
+	entry, known, _ := engine.Store.Get("file://a")
+	expected, _ := incremental.Hash(pushapi.Document{DocumentID: "file://a", Data: "a"})
+	if !known || entry.Hash != expected {
+		t.Errorf("unexpected entry.  expected %v, actual %+v", expected, entry)
+	}
+}
+
+func TestSyncItemsSkipsUnchangedVersions(t *testing.T) {
+	engine := &incremental.Engine{
+		Client:   &pushapitest.BatchClient{},
+		SourceID: "mysource",
+		Store:    incremental.NewMemoryStore(),
+	}
+
+	built := 0
+	sync := func(version, data string) incremental.Stats {
+		stats, err := engine.SyncItems(func(add func(incremental.Item) error) error {
+			return add(incremental.Item{
+				DocumentID: "file://a",
+				Version:    version,
+				Build: func() (*pushapi.Document, error) {
+					built++
+					return &pushapi.Document{DocumentID: "file://a", Data: data}, nil
+				},
+			})
+		})
+		if err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+		return stats
+	}
+
+	if stats := sync("v1", "a"); stats.New != 1 || built != 1 {
+		t.Fatalf("unexpected stats.  expected 1 new document built, actual %+v built %v times", stats, built)
+	}
+	if stats := sync("v1", "a"); stats.Unchanged != 1 || built != 1 {
+		t.Errorf("unexpected stats.  expected 1 unchanged document not built, actual %+v built %v times", stats, built)
+	}
+	// A new version with the same content is not pushed, its version is saved
+	if stats := sync("v2", "a"); stats.Unchanged != 1 || stats.Push.Batches != 0 || built != 2 {
+		t.Errorf("unexpected stats.  expected 1 unchanged document built, actual %+v built %v times", stats, built)
+	}
+	if stats := sync("v2", "a"); stats.Unchanged != 1 || built != 2 {
+		t.Errorf("unexpected stats.  expected 1 unchanged document not built, actual %+v built %v times", stats, built)
+	}
+	if stats := sync("v3", "b"); stats.Changed != 1 || built != 3 {
+		t.Errorf("unexpected stats.  expected 1 changed document, actual %+v built %v times", stats, built)
+	}
+}
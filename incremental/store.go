@@ -0,0 +1,224 @@
+package incremental
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Entry is what the state store knows about a pushed document
+type Entry struct {
+	// Hash is the hash of the content last pushed for the document
+	Hash string `json:"hash"`
+	// Version is the version of the item the document was built from, see
+	// Item
+	Version string `json:"version,omitempty"`
+	// OrderingID is the ordering ID of the batch the document was last
+	// pushed with
+	OrderingID int64 `json:"orderingId,omitempty"`
+}
+
+// StateStore keeps the state of every document pushed to a source.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Get returns the entry of the document, and false if it is unknown
+	Get(documentID string) (Entry, bool, error)
+	// Put saves the entry of the document
+	Put(documentID string, e Entry) error
+	// Delete forgets the document
+	Delete(documentID string) error
+	// DocumentIDs returns the IDs of every known document
+	DocumentIDs() ([]string, error)
+	// Close releases the resources of the store
+	Close() error
+}
+
+// NewMemoryStore returns a StateStore kept in memory, mostly useful for tests
+func NewMemoryStore() StateStore {
+	return &memoryStore{entries: map[string]Entry{}}
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+func (s *memoryStore) Get(documentID string) (Entry, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[documentID]
+	return e, ok, nil
+}
+
+func (s *memoryStore) Put(documentID string, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[documentID] = e
+	return nil
+}
+
+func (s *memoryStore) Delete(documentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, documentID)
+	return nil
+}
+
+func (s *memoryStore) DocumentIDs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// fileRecord is a line of the file store log
+type fileRecord struct {
+	DocumentID string `json:"id"`
+	Entry      *Entry `json:"entry,omitempty"`
+}
+
+// OpenFileStore opens the StateStore persisted in the file, creating it if it
+// does not exist. Every change is appended to the file, and the file is
+// compacted when the store is opened, so a store survives a crash with every
+// change written before it.
+func OpenFileStore(path string) (StateStore, error) {
+	s := &fileStore{memoryStore: memoryStore{entries: map[string]Entry{}}, path: path}
+
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		err = s.load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid state store %s: %v", path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type fileStore struct {
+	memoryStore
+	path string
+	file *os.File
+	w    *bufio.Writer
+}
+
+func (s *fileStore) Put(documentID string, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(fileRecord{DocumentID: documentID, Entry: &e}); err != nil {
+		return err
+	}
+	s.entries[documentID] = e
+	return nil
+}
+
+func (s *fileStore) Delete(documentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(fileRecord{DocumentID: documentID}); err != nil {
+		return err
+	}
+	delete(s.entries, documentID)
+	return nil
+}
+
+func (s *fileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+func (s *fileStore) append(r fileRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func (s *fileStore) load(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	for {
+		record := fileRecord{}
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			// The last line was being written when the process stopped
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if record.Entry == nil {
+			delete(s.entries, record.DocumentID)
+		} else {
+			s.entries[record.DocumentID] = *record.Entry
+		}
+	}
+}
+
+// compact rewrites the file with a single record per known document and
+// opens it for appending
+func (s *fileStore) compact() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for id, e := range s.entries {
+		entry := e
+		if err := encoder.Encode(fileRecord{DocumentID: id, Entry: &entry}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	s.w = bufio.NewWriter(s.file)
+	return nil
+}
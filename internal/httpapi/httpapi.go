@@ -0,0 +1,64 @@
+// Package httpapi sends the JSON requests of the clients of the Push API and
+// of the platform API, and turns their failures into APIError.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// APIError is returned when the Push API, or the platform API, does not
+// accept a request
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// ErrorCode is the error code returned by the service, if any
+	ErrorCode string `json:"errorCode"`
+	// Message is the error message returned by the service, if any
+	Message string `json:"message"`
+	// Body is the raw body of the response
+	Body string
+}
+
+// NewAPIError returns the APIError of a response, with the error code and
+// message of its body if it has them
+func NewAPIError(statusCode int, body []byte) *APIError {
+	e := &APIError{StatusCode: statusCode, Body: string(body)}
+	json.Unmarshal(body, e)
+	return e
+}
+
+func (e *APIError) Error() string {
+	if len(e.ErrorCode) != 0 {
+		return fmt.Sprintf("%s: %s", e.ErrorCode, e.Message)
+	}
+	if len(e.Body) != 0 {
+		return e.Body
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Send sends the JSON request with the HTTP client and returns the body of the
+// response, or an *APIError if the response is not a success
+func Send(httpClient *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, NewAPIError(resp.StatusCode, body)
+	}
+
+	return body, nil
+}
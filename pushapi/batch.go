@@ -0,0 +1,73 @@
+package pushapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DeletedDocument is a document to delete in a batch
+type DeletedDocument struct {
+	DocumentID string `json:"documentId"`
+	// DeleteChildren also deletes the documents having this document as
+	// parent, recursively
+	DeleteChildren bool `json:"deleteChildren,omitempty"`
+}
+
+// Batch is a set of documents to add, update or delete in a single request
+type Batch struct {
+	// AddOrUpdate are the documents to add or update
+	AddOrUpdate []Document `json:"addOrUpdate,omitempty"`
+	// Delete are the documents to delete
+	Delete []DeletedDocument `json:"delete,omitempty"`
+	// OrderingID is the ordering ID of every operation of the batch,
+	// generated if zero and AutoOrderingID is set
+	OrderingID int64 `json:"-"`
+}
+
+// BatchPush uploads the batch to a file container, then asks the Push API to
+// apply it to the source
+func (c *client) BatchPush(b Batch, sourceID string) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	for _, d := range b.AddOrUpdate {
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need to provide a documentID")
+		}
+	}
+	for _, d := range b.Delete {
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need a documentID")
+		}
+	}
+
+	if c.validate {
+		if err := validateBatch(b); err != nil {
+			return err
+		}
+	}
+
+	fileID, err := c.uploadPayload(b)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("fileId", fileID)
+	if orderingID := c.orderingID(b.OrderingID); orderingID != 0 {
+		query.Set("orderingId", strconv.FormatInt(orderingID, 10))
+	}
+
+	endpoint := fmt.Sprintf("%s%s/sources/%s/documents/batch?%s",
+		c.endpoint, c.organizationid, url.PathEscape(sourceID), query.Encode())
+	req, err := http.NewRequest("PUT", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
+}
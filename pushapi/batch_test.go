@@ -0,0 +1,123 @@
+package pushapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// fakePushAPI stands in for the Push API and the file container storage
+type fakePushAPI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	uploads  map[string][]byte
+	requests []string
+	// bodies are the bodies of the requests
+	bodies []string
+}
+
+func (f *fakePushAPI) record(r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	f.bodies = append(f.bodies, string(body))
+}
+
+func newFakePushAPI() *fakePushAPI {
+	f := &fakePushAPI{uploads: map[string][]byte{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/myorg/files", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"uploadUri":       f.URL + "/upload/file1",
+			"fileId":          "file1",
+			"requiredHeaders": map[string]string{"x-amz-server-side-encryption": "AES256"},
+		})
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("x-amz-server-side-encryption") != "AES256" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		f.mu.Lock()
+		f.uploads[r.URL.Path] = body
+		f.mu.Unlock()
+	})
+	mux.HandleFunc("/myorg/sources/", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		if !strings.HasSuffix(r.URL.Path, "/stream/open") && !strings.HasSuffix(r.URL.Path, "/chunk") {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		f.mu.Lock()
+		chunk := fmt.Sprintf("chunk%d", len(f.requests))
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"streamId":        "stream1",
+			"uploadUri":       f.URL + "/upload/" + chunk,
+			"fileId":          chunk,
+			"requiredHeaders": map[string]string{"x-amz-server-side-encryption": "AES256"},
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakePushAPI) client(t *testing.T) pushapi.Client {
+	c, err := pushapi.NewClient(pushapi.Config{
+		Endpoint:         f.URL + "/",
+		PlatformEndpoint: f.URL + "/platform/",
+		OrganizationID:   "myorg",
+		APIKey:           "key",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return c
+}
+
+func TestBatchPush(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	batch := pushapi.Batch{
+		AddOrUpdate: []pushapi.Document{{DocumentID: "file://a", Title: "A"}},
+		Delete:      []pushapi.DeletedDocument{{DocumentID: "file://b"}},
+	}
+	if err := server.client(t).BatchPush(batch, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expectedRequests := []string{
+		"POST /myorg/files",
+		"PUT /myorg/sources/mysource/documents/batch?fileId=file1",
+	}
+	if len(server.requests) != len(expectedRequests) {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", expectedRequests, server.requests)
+	}
+	for i, expected := range expectedRequests {
+		if server.requests[i] != expected {
+			t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[i])
+		}
+	}
+
+	assertJSONEqual(t, `{
+		"addOrUpdate": [{"documentId": "file://a", "title": "A"}],
+		"delete": [{"documentId": "file://b"}]
+	}`, string(server.uploads["/upload/file1"]))
+}
@@ -0,0 +1,115 @@
+package pushapi
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxCompressedBinaryDataSize is the maximum size, once compressed and base64
+// encoded, of the binary data of a document
+const MaxCompressedBinaryDataSize = 256 * 1024 * 1024
+
+// ErrBinaryDataTooLarge is returned when the encoded binary data of a
+// document is larger than MaxCompressedBinaryDataSize
+var ErrBinaryDataTooLarge = fmt.Errorf("the compressed binary data is larger than %d bytes", MaxCompressedBinaryDataSize)
+
+// SetBinaryData compresses the content of r with the compression type and
+// sets it, base64 encoded, as the compressed binary data of the document. The
+// content is streamed through the compression and the encoding into the
+// string of the document, so the encoded data is held in memory only once.
+func (d *Document) SetBinaryData(r io.Reader, compression CompressionType) error {
+	return d.setBinaryData(r, compression, MaxCompressedBinaryDataSize)
+}
+
+func (d *Document) setBinaryData(r io.Reader, compression CompressionType, limit int) error {
+	if len(compression) == 0 {
+		compression = CompressionZlib
+	}
+
+	encoded := &limitedBuilder{limit: limit}
+	encoder := base64.NewEncoder(base64.StdEncoding, encoded)
+
+	compressor, err := newCompressor(encoder, compression)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(compressor, r); err != nil {
+		return err
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	// String returns the built string without copying it
+	d.CompressedBinaryData = encoded.String()
+	d.CompressionType = compression
+	return nil
+}
+
+// SetBinaryDataFromFile sets the content of the file as the compressed binary
+// data of the document, see SetBinaryData. The file extension of the document
+// is inferred from the path if it is not already set.
+func (d *Document) SetBinaryDataFromFile(path string, compression CompressionType) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := d.SetBinaryData(f, compression); err != nil {
+		return err
+	}
+
+	if len(d.FileExtension) == 0 {
+		d.FileExtension = strings.ToLower(filepath.Ext(path))
+	}
+	return nil
+}
+
+func newCompressor(w io.Writer, compression CompressionType) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionZlib:
+		return zlib.NewWriter(w), nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionDeflate:
+		return flate.NewWriter(w, flate.DefaultCompression)
+	case CompressionUncompressed:
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression type %q", compression)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// limitedBuilder is a strings.Builder failing with ErrBinaryDataTooLarge once
+// more than limit bytes are written to it
+type limitedBuilder struct {
+	strings.Builder
+	limit int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, ErrBinaryDataTooLarge
+	}
+	return b.Builder.Write(p)
+}
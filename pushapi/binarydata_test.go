@@ -0,0 +1,134 @@
+package pushapi_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// decompress decodes and decompresses the binary data of the document
+func decompress(t *testing.T, d pushapi.Document) string {
+	decoded, err := base64.StdEncoding.DecodeString(d.CompressedBinaryData)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	var r io.Reader
+	switch d.CompressionType {
+	case pushapi.CompressionZlib:
+		r, err = zlib.NewReader(bytes.NewReader(decoded))
+	case pushapi.CompressionGzip:
+		r, err = gzip.NewReader(bytes.NewReader(decoded))
+	case pushapi.CompressionDeflate:
+		r = flate.NewReader(bytes.NewReader(decoded))
+	case pushapi.CompressionUncompressed:
+		r = bytes.NewReader(decoded)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return string(content)
+}
+
+func TestSetBinaryData(t *testing.T) {
+	content := strings.Repeat("Hello, world! ", 1000)
+
+	compressions := []pushapi.CompressionType{
+		pushapi.CompressionZlib,
+		pushapi.CompressionGzip,
+		pushapi.CompressionDeflate,
+		pushapi.CompressionUncompressed,
+	}
+	for _, compression := range compressions {
+		d := pushapi.Document{DocumentID: "file://a"}
+		if err := d.SetBinaryData(strings.NewReader(content), compression); err != nil {
+			t.Fatalf("unexpected error for %v.  expected %v, actual %v", compression, nil, err)
+		}
+		if d.CompressionType != compression {
+			t.Errorf("unexpected compression type.  expected %v, actual %v", compression, d.CompressionType)
+		}
+		if actual := decompress(t, d); actual != content {
+			t.Errorf("unexpected content for %v.  expected %d bytes, actual %d bytes", compression, len(content), len(actual))
+		}
+	}
+}
+
+func TestSetBinaryDataDefaultsToZlib(t *testing.T) {
+	d := pushapi.Document{}
+	if err := d.SetBinaryData(strings.NewReader("Hello"), ""); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if d.CompressionType != pushapi.CompressionZlib {
+		t.Errorf("unexpected compression type.  expected %v, actual %v", pushapi.CompressionZlib, d.CompressionType)
+	}
+	if actual := decompress(t, d); actual != "Hello" {
+		t.Errorf("unexpected content.  expected %v, actual %v", "Hello", actual)
+	}
+}
+
+func TestSetBinaryDataUnsupportedCompression(t *testing.T) {
+	d := pushapi.Document{}
+	if err := d.SetBinaryData(strings.NewReader("Hello"), "LZMA"); err == nil {
+		t.Errorf("expected an error for an unsupported compression")
+	}
+}
+
+func TestSetBinaryDataTooLarge(t *testing.T) {
+	// 30 bytes are 40 bytes once base64 encoded
+	content := strings.Repeat("a", 30)
+
+	d := pushapi.Document{}
+	err := d.SetBinaryDataWithLimit(strings.NewReader(content), pushapi.CompressionUncompressed, 39)
+	if err != pushapi.ErrBinaryDataTooLarge {
+		t.Errorf("unexpected error.  expected %v, actual %v", pushapi.ErrBinaryDataTooLarge, err)
+	}
+	if len(d.CompressedBinaryData) != 0 {
+		t.Errorf("expected no binary data to be set, actual %d bytes", len(d.CompressedBinaryData))
+	}
+
+	if err := d.SetBinaryDataWithLimit(strings.NewReader(content), pushapi.CompressionUncompressed, 40); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if actual := decompress(t, d); actual != content {
+		t.Errorf("unexpected content.  expected %v, actual %v", content, actual)
+	}
+}
+
+func TestSetBinaryDataFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binarydata")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Report.PDF")
+	if err := ioutil.WriteFile(path, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	d := pushapi.Document{}
+	if err := d.SetBinaryDataFromFile(path, pushapi.CompressionGzip); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if d.FileExtension != ".pdf" {
+		t.Errorf("unexpected file extension.  expected %v, actual %v", ".pdf", d.FileExtension)
+	}
+	if actual := decompress(t, d); actual != "%PDF-1.4" {
+		t.Errorf("unexpected content.  expected %v, actual %v", "%PDF-1.4", actual)
+	}
+}
@@ -0,0 +1,325 @@
+package pushapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CaptureOperation is the name of an operation captured by a CaptureClient
+type CaptureOperation string
+
+const (
+	// CapturePushDocument is a document pushed with PushDocument
+	CapturePushDocument CaptureOperation = "pushDocument"
+	// CaptureDeleteDocument is a document deleted with DeleteDocument
+	CaptureDeleteDocument CaptureOperation = "deleteDocument"
+	// CaptureDeleteOlderThan is a call to DeleteOlderThan
+	CaptureDeleteOlderThan CaptureOperation = "deleteOlderThan"
+	// CaptureBatchPush is a batch sent with BatchPush
+	CaptureBatchPush CaptureOperation = "batchPush"
+	// CaptureSetSourceStatus is a call to SetSourceStatus
+	CaptureSetSourceStatus CaptureOperation = "setSourceStatus"
+	// CapturePushIdentity is an identity pushed with PushIdentity
+	CapturePushIdentity CaptureOperation = "pushIdentity"
+	// CaptureDeleteIdentity is an identity deleted with DeleteIdentity
+	CaptureDeleteIdentity CaptureOperation = "deleteIdentity"
+	// CapturePushIdentityBatch is a batch sent with PushIdentityBatch
+	CapturePushIdentityBatch CaptureOperation = "pushIdentityBatch"
+	// CaptureDeleteIdentitiesOlderThan is a call to DeleteIdentitiesOlderThan
+	CaptureDeleteIdentitiesOlderThan CaptureOperation = "deleteIdentitiesOlderThan"
+	// CaptureRefreshSecurityProvider is a call to RefreshSecurityProvider
+	CaptureRefreshSecurityProvider CaptureOperation = "refreshSecurityProvider"
+)
+
+// CaptureEntry is an operation captured by a CaptureClient, with the payload
+// that would have been sent
+type CaptureEntry struct {
+	Operation      CaptureOperation `json:"operation"`
+	SourceID       string           `json:"sourceId,omitempty"`
+	ProviderID     string           `json:"providerId,omitempty"`
+	DocumentID     string           `json:"documentId,omitempty"`
+	OrderingID     int64            `json:"orderingId,omitempty"`
+	DeleteChildren bool             `json:"deleteChildren,omitempty"`
+	Status         SourceStatus     `json:"status,omitempty"`
+	Payload        interface{}      `json:"payload,omitempty"`
+}
+
+// CaptureSummary counts the operations captured by a CaptureClient
+type CaptureSummary struct {
+	Operations        int `json:"operations"`
+	DocumentsPushed   int `json:"documentsPushed"`
+	DocumentsDeleted  int `json:"documentsDeleted"`
+	Batches           int `json:"batches"`
+	IdentitiesPushed  int `json:"identitiesPushed"`
+	IdentitiesDeleted int `json:"identitiesDeleted"`
+}
+
+func (s CaptureSummary) String() string {
+	return fmt.Sprintf("%d operations: %d documents pushed, %d documents deleted in %d batches, %d identities pushed, %d identities deleted",
+		s.Operations, s.DocumentsPushed, s.DocumentsDeleted, s.Batches, s.IdentitiesPushed, s.IdentitiesDeleted)
+}
+
+// CaptureClient is a Client writing every operation to a file or a directory
+// instead of sending it to the Push API, to review or diff what a connector
+// sends. Ordering IDs are never generated, so that captures can be compared.
+// It is safe for concurrent use.
+type CaptureClient struct {
+	// Validate checks the documents with Validate, like Config.Validate
+	Validate bool
+
+	mu      sync.Mutex
+	write   func(e CaptureEntry) error
+	summary CaptureSummary
+}
+
+// NewCaptureClient returns a CaptureClient writing the operations to w, one
+// JSON entry per line
+func NewCaptureClient(w io.Writer) *CaptureClient {
+	encoder := json.NewEncoder(w)
+	return &CaptureClient{write: func(e CaptureEntry) error {
+		return encoder.Encode(e)
+	}}
+}
+
+// NewCaptureDirectoryClient returns a CaptureClient writing every operation
+// to its own indented JSON file in dir, named after its position and
+// operation
+func NewCaptureDirectoryClient(dir string) (*CaptureClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &CaptureClient{}
+	c.write = func(e CaptureEntry) error {
+		marshalledEntry, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%06d-%s.json", c.summary.Operations, e.Operation)
+		return ioutil.WriteFile(filepath.Join(dir, name), append(marshalledEntry, '\n'), 0644)
+	}
+	return c, nil
+}
+
+// Summary returns the counts of the operations captured so far
+func (c *CaptureClient) Summary() CaptureSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.summary
+}
+
+// capture writes the entry and counts it, count updates the summary once the
+// entry is written
+func (c *CaptureClient) capture(e CaptureEntry, count func(s *CaptureSummary)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summary.Operations++
+	if err := c.write(e); err != nil {
+		c.summary.Operations--
+		return err
+	}
+	if count != nil {
+		count(&c.summary)
+	}
+	return nil
+}
+
+// PushDocument captures the document
+func (c *CaptureClient) PushDocument(d Document, sourceID string) (string, error) {
+	if len(sourceID) == 0 {
+		return "", errors.New("You need a sourceID")
+	}
+	if len(d.DocumentID) == 0 {
+		return "", errors.New("You need to provide a documentID")
+	}
+	if c.Validate {
+		if err := Validate(d); err != nil {
+			return "", err
+		}
+		marshalledDocument, err := json.Marshal(d.body())
+		if err != nil {
+			return "", err
+		}
+		if err := validatePushDocumentSize(len(marshalledDocument)); err != nil {
+			return "", err
+		}
+	}
+
+	return "", c.capture(CaptureEntry{
+		Operation:  CapturePushDocument,
+		SourceID:   sourceID,
+		DocumentID: d.DocumentID,
+		OrderingID: d.OrderingID,
+		Payload:    d,
+	}, func(s *CaptureSummary) { s.DocumentsPushed++ })
+}
+
+// DeleteDocument captures the deletion
+func (c *CaptureClient) DeleteDocument(documentID, sourceID string) error {
+	return c.DeleteDocumentWithOptions(documentID, sourceID, DeleteOptions{})
+}
+
+// DeleteDocumentWithOptions captures the deletion
+func (c *CaptureClient) DeleteDocumentWithOptions(documentID, sourceID string, o DeleteOptions) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	if len(documentID) == 0 {
+		return errors.New("You need a documentID")
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:      CaptureDeleteDocument,
+		SourceID:       sourceID,
+		DocumentID:     documentID,
+		OrderingID:     o.OrderingID,
+		DeleteChildren: o.DeleteChildren,
+	}, func(s *CaptureSummary) { s.DocumentsDeleted++ })
+}
+
+// DeleteOlderThan captures the deletion
+func (c *CaptureClient) DeleteOlderThan(sourceID string, orderingID int64) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CaptureDeleteOlderThan,
+		SourceID:   sourceID,
+		OrderingID: orderingID,
+	}, nil)
+}
+
+// BatchPush captures the batch
+func (c *CaptureClient) BatchPush(b Batch, sourceID string) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	for _, d := range b.AddOrUpdate {
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need to provide a documentID")
+		}
+	}
+	for _, d := range b.Delete {
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need a documentID")
+		}
+	}
+	if c.Validate {
+		if err := validateBatch(b); err != nil {
+			return err
+		}
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CaptureBatchPush,
+		SourceID:   sourceID,
+		OrderingID: b.OrderingID,
+		Payload:    b,
+	}, func(s *CaptureSummary) {
+		s.Batches++
+		s.DocumentsPushed += len(b.AddOrUpdate)
+		s.DocumentsDeleted += len(b.Delete)
+	})
+}
+
+// SetSourceStatus captures the status change
+func (c *CaptureClient) SetSourceStatus(sourceID string, status SourceStatus) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	return c.capture(CaptureEntry{
+		Operation: CaptureSetSourceStatus,
+		SourceID:  sourceID,
+		Status:    status,
+	}, nil)
+}
+
+// PushIdentity captures the identity
+func (c *CaptureClient) PushIdentity(i Identity, providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	if err := i.Validate(); err != nil {
+		return err
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CapturePushIdentity,
+		ProviderID: providerID,
+		OrderingID: i.OrderingID,
+		Payload:    i,
+	}, func(s *CaptureSummary) { s.IdentitiesPushed++ })
+}
+
+// DeleteIdentity captures the deletion of the identity
+func (c *CaptureClient) DeleteIdentity(i Identity, providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	if err := (Identity{Identity: i.Identity}).Validate(); err != nil {
+		return err
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CaptureDeleteIdentity,
+		ProviderID: providerID,
+		OrderingID: i.OrderingID,
+		Payload:    Identity{Identity: i.Identity},
+	}, func(s *CaptureSummary) { s.IdentitiesDeleted++ })
+}
+
+// PushIdentityBatch captures the batch, the options other than OrderingID
+// are ignored
+func (c *CaptureClient) PushIdentityBatch(b IdentityBatch, providerID string, o BatchOptions) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CapturePushIdentityBatch,
+		ProviderID: providerID,
+		OrderingID: o.OrderingID,
+		Payload:    b,
+	}, func(s *CaptureSummary) {
+		s.Batches++
+		s.IdentitiesPushed += len(b.Members) + len(b.Mappings)
+		s.IdentitiesDeleted += len(b.Deleted)
+	})
+}
+
+// DeleteIdentitiesOlderThan captures the deletion
+func (c *CaptureClient) DeleteIdentitiesOlderThan(providerID string, orderingID int64) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CaptureDeleteIdentitiesOlderThan,
+		ProviderID: providerID,
+		OrderingID: orderingID,
+	}, nil)
+}
+
+// RefreshSecurityProvider captures the refresh
+func (c *CaptureClient) RefreshSecurityProvider(providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	return c.capture(CaptureEntry{
+		Operation:  CaptureRefreshSecurityProvider,
+		ProviderID: providerID,
+	}, nil)
+}
@@ -0,0 +1,115 @@
+package pushapi_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+var _ pushapi.Client = &pushapi.CaptureClient{}
+
+func TestCaptureClient(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := pushapi.NewCaptureClient(buf)
+
+	if _, err := c.PushDocument(pushapi.Document{DocumentID: "file://a", Title: "A", OrderingID: 1}, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := c.DeleteDocumentWithOptions("file://b", "mysource", pushapi.DeleteOptions{DeleteChildren: true}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	batch := pushapi.Batch{
+		AddOrUpdate: []pushapi.Document{{DocumentID: "file://c"}, {DocumentID: "file://d"}},
+		Delete:      []pushapi.DeletedDocument{{DocumentID: "file://e"}},
+	}
+	if err := c.BatchPush(batch, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if _, err := c.PushDocument(pushapi.Document{}, "mysource"); err == nil {
+		t.Errorf("expected an error for a document without ID")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected number of entries.  expected %v, actual %v", 3, len(lines))
+	}
+	assertJSONEqual(t, `{
+		"operation": "pushDocument",
+		"sourceId": "mysource",
+		"documentId": "file://a",
+		"orderingId": 1,
+		"payload": {"documentId": "file://a", "title": "A"}
+	}`, lines[0])
+	assertJSONEqual(t, `{
+		"operation": "deleteDocument",
+		"sourceId": "mysource",
+		"documentId": "file://b",
+		"deleteChildren": true
+	}`, lines[1])
+
+	expected := pushapi.CaptureSummary{Operations: 3, DocumentsPushed: 3, DocumentsDeleted: 2, Batches: 1}
+	if summary := c.Summary(); summary != expected {
+		t.Errorf("unexpected summary.  expected %v, actual %v", expected, summary)
+	}
+}
+
+func TestCaptureClientValidates(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := pushapi.NewCaptureClient(buf)
+	c.Validate = true
+
+	// The real client refuses to push a document this large, it must be sent
+	// with BatchPush
+	large := pushapi.Document{DocumentID: "file://large", Data: strings.Repeat("a", pushapi.MaxPushDocumentSize)}
+	if _, err := c.PushDocument(large, "mysource"); err == nil {
+		t.Errorf("expected an error for a document larger than %v bytes", pushapi.MaxPushDocumentSize)
+	}
+	if err := c.BatchPush(pushapi.Batch{AddOrUpdate: []pushapi.Document{large}}, "mysource"); err != nil {
+		t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if err := c.DeleteIdentity(pushapi.Identity{}, "myprovider"); err == nil {
+		t.Errorf("expected an error for an identity without name")
+	}
+
+	if summary := c.Summary(); summary.Operations != 1 {
+		t.Errorf("unexpected summary.  expected %v operation, actual %+v", 1, summary)
+	}
+}
+
+func TestCaptureDirectoryClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := pushapi.NewCaptureDirectoryClient(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := c.PushIdentity(pushapi.Identity{Identity: pushapi.PermissionIdentity{Name: "a@example.com", Type: pushapi.IdentityTypeUser}}, "myprovider"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := c.RefreshSecurityProvider("myprovider"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	expectedNames := []string{"000001-pushIdentity.json", "000002-refreshSecurityProvider.json"}
+	if strings.Join(names, ",") != strings.Join(expectedNames, ",") {
+		t.Errorf("unexpected files.  expected %v, actual %v", expectedNames, names)
+	}
+}
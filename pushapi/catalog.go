@@ -0,0 +1,451 @@
+package pushapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// MaxCatalogChunkSize is the maximum size in bytes of a chunk of a
+	// catalog stream or update
+	MaxCatalogChunkSize = 256 * 1024 * 1024
+	// objectTypeField is the field telling the catalog items apart
+	objectTypeField = "objecttype"
+)
+
+// CatalogClient is the client to load the items of catalog sources through
+// the stream API. Catalog sources do not accept the document operations of
+// Client.
+type CatalogClient interface {
+	// OpenStream opens a stream replacing every item of the source with the
+	// items added to it, once it is closed
+	OpenStream(sourceID string, o StreamOptions) (*Stream, error)
+	// Update merges the items of the update with the items of the source
+	Update(sourceID string, u CatalogUpdate, o StreamOptions) error
+}
+
+// StreamOptions are the options of a catalog stream or update
+type StreamOptions struct {
+	// MaxChunkSize is the maximum size in bytes of the chunks uploaded,
+	// MaxCatalogChunkSize if zero
+	MaxChunkSize int
+}
+
+func (o StreamOptions) maxChunkSize() int {
+	if o.MaxChunkSize <= 0 || o.MaxChunkSize > MaxCatalogChunkSize {
+		return MaxCatalogChunkSize
+	}
+	return o.MaxChunkSize
+}
+
+// NewCatalogClient initializes a new catalog client with the config param
+func NewCatalogClient(c Config) (CatalogClient, error) {
+	pushClient, err := NewClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return pushClient.(*client), nil
+}
+
+// CatalogItem is an item of a catalog source
+type CatalogItem interface {
+	// Document returns the document pushed for the item
+	Document() Document
+}
+
+// Product is a product of a catalog source
+type Product struct {
+	// DocumentID is the unique ID of the item, it must be a valid URI
+	DocumentID string
+	// ProductID is the ID shared by the product and its variants
+	ProductID string
+	// PermanentID is the ID used by the analytics, ProductID if empty
+	PermanentID string
+	// Name is the name of the product, also used as title
+	Name string
+	// Description describes the product
+	Description string
+	// Brand is the brand of the product
+	Brand string
+	// Category is the hierarchical category of the product
+	Category []string
+	// Price is the price of the product
+	Price float64
+	// PromoPrice is the discounted price of the product, if any
+	PromoPrice float64
+	// Images are the URLs of the images of the product
+	Images []string
+	// ClickableURI is the URL of the product page
+	ClickableURI string
+	// Fields holds the other metadata of the product, keyed by field name
+	Fields map[string]interface{}
+}
+
+// Document returns the document pushed for the product
+func (p Product) Document() Document {
+	permanentID := p.PermanentID
+	if len(permanentID) == 0 {
+		permanentID = p.ProductID
+	}
+
+	d := newCatalogDocument("Product", p.DocumentID, p.Name, p.ClickableURI, p.Fields)
+	setField(d.Fields, "ec_product_id", p.ProductID)
+	setField(d.Fields, "permanentid", permanentID)
+	setField(d.Fields, "ec_name", p.Name)
+	setField(d.Fields, "ec_description", p.Description)
+	setField(d.Fields, "ec_brand", p.Brand)
+	setField(d.Fields, "ec_category", p.Category)
+	setField(d.Fields, "ec_price", p.Price)
+	setField(d.Fields, "ec_promo_price", p.PromoPrice)
+	setField(d.Fields, "ec_images", p.Images)
+	return d
+}
+
+// Variant is a variant of a product of a catalog source
+type Variant struct {
+	// DocumentID is the unique ID of the item, it must be a valid URI
+	DocumentID string
+	// ProductID is the ID of the product of the variant
+	ProductID string
+	// VariantID is the ID of the variant, usually its SKU
+	VariantID string
+	// PermanentID is the ID used by the analytics, VariantID if empty
+	PermanentID string
+	// Name is the name of the variant, also used as title
+	Name string
+	// Price is the price of the variant
+	Price float64
+	// Fields holds the other metadata of the variant, keyed by field name
+	Fields map[string]interface{}
+}
+
+// Document returns the document pushed for the variant
+func (v Variant) Document() Document {
+	permanentID := v.PermanentID
+	if len(permanentID) == 0 {
+		permanentID = v.VariantID
+	}
+
+	d := newCatalogDocument("Variant", v.DocumentID, v.Name, "", v.Fields)
+	setField(d.Fields, "ec_product_id", v.ProductID)
+	setField(d.Fields, "ec_variant_id", v.VariantID)
+	setField(d.Fields, "permanentid", permanentID)
+	setField(d.Fields, "ec_name", v.Name)
+	setField(d.Fields, "ec_price", v.Price)
+	return d
+}
+
+// Availability is a location where items of a catalog source are available,
+// such as a store
+type Availability struct {
+	// DocumentID is the unique ID of the item, it must be a valid URI
+	DocumentID string
+	// AvailabilityID is the ID of the availability
+	AvailabilityID string
+	// Name is the name of the availability, also used as title
+	Name string
+	// AvailableItems are the product or variant IDs available
+	AvailableItems []string
+	// Fields holds the other metadata of the availability, keyed by field
+	// name
+	Fields map[string]interface{}
+}
+
+// Document returns the document pushed for the availability
+func (a Availability) Document() Document {
+	d := newCatalogDocument("Availability", a.DocumentID, a.Name, "", a.Fields)
+	setField(d.Fields, "ec_availability_id", a.AvailabilityID)
+	setField(d.Fields, "ec_available_items", a.AvailableItems)
+	return d
+}
+
+func newCatalogDocument(objectType, documentID, title, clickableURI string, fields map[string]interface{}) Document {
+	d := Document{
+		DocumentID:   documentID,
+		Title:        title,
+		ClickableURI: clickableURI,
+		Fields:       map[string]interface{}{objectTypeField: objectType},
+	}
+	for name, value := range fields {
+		d.Fields[name] = value
+	}
+	return d
+}
+
+// setField sets the field unless the value is the zero value of its type
+func setField(fields map[string]interface{}, name string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		if len(v) == 0 {
+			return
+		}
+	case float64:
+		if v == 0 {
+			return
+		}
+	case []string:
+		if len(v) == 0 {
+			return
+		}
+	}
+	fields[name] = value
+}
+
+// CatalogUpdate is a set of items to merge with the items of a catalog source
+type CatalogUpdate struct {
+	// AddOrUpdate are the items to add or replace
+	AddOrUpdate []Document `json:"addOrUpdate,omitempty"`
+	// Delete are the items to delete
+	Delete []DeletedDocument `json:"delete,omitempty"`
+	// PartialUpdate are the fields of items to update, see NewPartialUpdates
+	PartialUpdate []PartialUpdate `json:"partialUpdate,omitempty"`
+}
+
+// Add adds the items to add or replace to the update
+func (u *CatalogUpdate) Add(items ...CatalogItem) {
+	for _, item := range items {
+		u.AddOrUpdate = append(u.AddOrUpdate, item.Document())
+	}
+}
+
+// Update splits the update in chunks of at most MaxChunkSize bytes, then
+// uploads and applies every chunk in order
+func (c *client) Update(sourceID string, u CatalogUpdate, o StreamOptions) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	var chunks []CatalogUpdate
+	chunk, size := CatalogUpdate{}, 0
+	add := func(itemSize int, appendItem func()) {
+		if size+itemSize > o.maxChunkSize() && size != 0 {
+			chunks = append(chunks, chunk)
+			chunk, size = CatalogUpdate{}, 0
+		}
+		appendItem()
+		size += itemSize
+	}
+
+	for _, d := range u.AddOrUpdate {
+		d := d
+		itemSize, err := c.catalogDocumentSize(d)
+		if err != nil {
+			return err
+		}
+		add(itemSize, func() { chunk.AddOrUpdate = append(chunk.AddOrUpdate, d) })
+	}
+	for _, d := range u.Delete {
+		d := d
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need a documentID")
+		}
+		add(len(d.DocumentID), func() { chunk.Delete = append(chunk.Delete, d) })
+	}
+	for _, p := range u.PartialUpdate {
+		p := p
+		if err := p.validate(); err != nil {
+			return err
+		}
+		marshalledUpdate, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		add(len(marshalledUpdate), func() { chunk.PartialUpdate = append(chunk.PartialUpdate, p) })
+	}
+	if size != 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	for _, chunk := range chunks {
+		fileID, err := c.uploadPayload(chunk)
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		query.Set("fileId", fileID)
+		req, err := http.NewRequest("PUT", c.streamEndpoint(sourceID, "update")+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		if _, err := c.sendRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// catalogDocumentSize checks the document and returns its size once
+// marshalled
+func (c *client) catalogDocumentSize(d Document) (int, error) {
+	if len(d.DocumentID) == 0 {
+		return 0, errors.New("You need to provide a documentID")
+	}
+	if c.validate {
+		if err := Validate(d); err != nil {
+			return 0, err
+		}
+	}
+
+	marshalledDocument, err := json.Marshal(d)
+	if err != nil {
+		return 0, err
+	}
+	return len(marshalledDocument), nil
+}
+
+func (c *client) streamEndpoint(sourceID, path string) string {
+	return fmt.Sprintf("%s%s/sources/%s/stream/%s", c.endpoint, c.organizationid, url.PathEscape(sourceID), path)
+}
+
+// streamContainer is the file container of a chunk of a stream
+type streamContainer struct {
+	fileContainer
+	StreamID string `json:"streamId"`
+}
+
+// Stream loads every item of a catalog source, the items of the source that
+// were not added to the stream are deleted when it is closed. A Stream is not
+// safe for concurrent use.
+type Stream struct {
+	client       *client
+	sourceID     string
+	streamID     string
+	maxChunkSize int
+	// container is the file container of the next chunk to upload
+	container *fileContainer
+	chunk     []json.RawMessage
+	size      int
+	closed    bool
+}
+
+// OpenStream opens a stream for the source
+func (c *client) OpenStream(sourceID string, o StreamOptions) (*Stream, error) {
+	if len(sourceID) == 0 {
+		return nil, errors.New("You need a sourceID")
+	}
+
+	container, err := c.streamContainer(c.streamEndpoint(sourceID, "open"))
+	if err != nil {
+		return nil, err
+	}
+	if len(container.StreamID) == 0 {
+		return nil, errors.New("no stream ID was returned")
+	}
+
+	return &Stream{
+		client:       c,
+		sourceID:     sourceID,
+		streamID:     container.StreamID,
+		maxChunkSize: o.maxChunkSize(),
+		container:    &container.fileContainer,
+	}, nil
+}
+
+// StreamID returns the ID of the stream
+func (s *Stream) StreamID() string {
+	return s.streamID
+}
+
+// Add adds the items to the stream
+func (s *Stream) Add(items ...CatalogItem) error {
+	for _, item := range items {
+		if err := s.AddDocument(item.Document()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddDocument adds the document to the stream, the current chunk is uploaded
+// first if the document does not fit in it
+func (s *Stream) AddDocument(d Document) error {
+	if s.closed {
+		return errors.New("the stream is closed")
+	}
+
+	size, err := s.client.catalogDocumentSize(d)
+	if err != nil {
+		return err
+	}
+	if s.size+size > s.maxChunkSize && len(s.chunk) != 0 {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+
+	marshalledDocument, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	s.chunk = append(s.chunk, marshalledDocument)
+	s.size += size
+	return nil
+}
+
+// Close uploads the last chunk and closes the stream, the source is then
+// replaced by the items of the stream
+func (s *Stream) Close() error {
+	if s.closed {
+		return nil
+	}
+	if len(s.chunk) != 0 {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	s.closed = true
+
+	req, err := http.NewRequest("POST", s.client.streamEndpoint(s.sourceID, url.PathEscape(s.streamID)+"/close"), nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.sendRequest(req)
+	return err
+}
+
+// flush uploads the current chunk
+func (s *Stream) flush() error {
+	if s.container == nil {
+		container, err := s.client.streamContainer(s.client.streamEndpoint(s.sourceID, url.PathEscape(s.streamID)+"/chunk"))
+		if err != nil {
+			return err
+		}
+		s.container = &container.fileContainer
+	}
+
+	payload, err := json.Marshal(struct {
+		AddOrUpdate []json.RawMessage `json:"addOrUpdate"`
+	}{s.chunk})
+	if err != nil {
+		return err
+	}
+	if err := s.client.upload(s.container, payload); err != nil {
+		return err
+	}
+
+	s.container, s.chunk, s.size = nil, nil, 0
+	return nil
+}
+
+// streamContainer asks the Push API for the file container of a chunk
+func (c *client) streamContainer(endpoint string) (*streamContainer, error) {
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	container := &streamContainer{}
+	if err := json.Unmarshal([]byte(resp), container); err != nil {
+		return nil, err
+	}
+	return container, nil
+}
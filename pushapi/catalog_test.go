@@ -0,0 +1,149 @@
+package pushapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func (f *fakePushAPI) catalogClient(t *testing.T) pushapi.CatalogClient {
+	c, err := pushapi.NewCatalogClient(pushapi.Config{
+		Endpoint:         f.URL + "/",
+		PlatformEndpoint: f.URL + "/platform/",
+		OrganizationID:   "myorg",
+		APIKey:           "key",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return c
+}
+
+func TestProductDocument(t *testing.T) {
+	d := pushapi.Product{
+		DocumentID: "product://shoe",
+		ProductID:  "shoe",
+		Name:       "Shoe",
+		Price:      49.99,
+		Category:   []string{"Apparel", "Apparel|Shoes"},
+		Fields:     map[string]interface{}{"color": "red"},
+	}.Document()
+
+	marshalled, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	assertJSONEqual(t, `{
+		"documentId": "product://shoe",
+		"title": "Shoe",
+		"objecttype": "Product",
+		"ec_product_id": "shoe",
+		"permanentid": "shoe",
+		"ec_name": "Shoe",
+		"ec_price": 49.99,
+		"ec_category": ["Apparel", "Apparel|Shoes"],
+		"color": "red"
+	}`, string(marshalled))
+}
+
+func TestStreamChunks(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	stream, err := server.catalogClient(t).OpenStream("mysource", pushapi.StreamOptions{MaxChunkSize: 150})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	err = stream.Add(
+		pushapi.Product{DocumentID: "product://a", ProductID: "a", Name: "A"},
+		pushapi.Variant{DocumentID: "variant://a1", ProductID: "a", VariantID: "a1"},
+		pushapi.Availability{DocumentID: "store://1", AvailabilityID: "1", AvailableItems: []string{"a1"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expectedRequests := []string{
+		"POST /myorg/sources/mysource/stream/open",
+		"POST /myorg/sources/mysource/stream/stream1/chunk",
+		"POST /myorg/sources/mysource/stream/stream1/chunk",
+		"POST /myorg/sources/mysource/stream/stream1/close",
+	}
+	if len(server.requests) != len(expectedRequests) {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", expectedRequests, server.requests)
+	}
+	for i, expected := range expectedRequests {
+		if server.requests[i] != expected {
+			t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[i])
+		}
+	}
+
+	assertJSONEqual(t, `{"addOrUpdate": [
+		{"documentId": "product://a", "title": "A", "objecttype": "Product", "ec_product_id": "a", "permanentid": "a", "ec_name": "A"}
+	]}`, string(server.uploads["/upload/chunk1"]))
+	assertJSONEqual(t, `{"addOrUpdate": [
+		{"documentId": "variant://a1", "objecttype": "Variant", "ec_product_id": "a", "ec_variant_id": "a1", "permanentid": "a1"}
+	]}`, string(server.uploads["/upload/chunk2"]))
+	assertJSONEqual(t, `{"addOrUpdate": [
+		{"documentId": "store://1", "objecttype": "Availability", "ec_availability_id": "1", "ec_available_items": ["a1"]}
+	]}`, string(server.uploads["/upload/chunk3"]))
+}
+
+func TestCatalogUpdate(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	update := pushapi.CatalogUpdate{Delete: []pushapi.DeletedDocument{{DocumentID: "product://b"}}}
+	update.Add(pushapi.Product{DocumentID: "product://a", ProductID: "a"})
+	if err := server.catalogClient(t).Update("mysource", update, pushapi.StreamOptions{}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expectedRequests := []string{
+		"POST /myorg/files",
+		"PUT /myorg/sources/mysource/stream/update?fileId=file1",
+	}
+	if len(server.requests) != len(expectedRequests) {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", expectedRequests, server.requests)
+	}
+	for i, expected := range expectedRequests {
+		if server.requests[i] != expected {
+			t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[i])
+		}
+	}
+
+	assertJSONEqual(t, `{
+		"addOrUpdate": [{"documentId": "product://a", "objecttype": "Product", "ec_product_id": "a", "permanentid": "a"}],
+		"delete": [{"documentId": "product://b"}]
+	}`, string(server.uploads["/upload/file1"]))
+}
+
+func TestCatalogPartialUpdate(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	update := pushapi.CatalogUpdate{}
+	update.AddPartialUpdates(
+		pushapi.NewPartialUpdates("product://a").Replace("ec_price", 39.99).AddTo("ec_images", "https://img/a2.png"),
+		pushapi.NewPartialUpdates("store://1").RemoveFrom("ec_available_items", "a1", "a2"),
+	)
+	if err := server.catalogClient(t).Update("mysource", update, pushapi.StreamOptions{}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	assertJSONEqual(t, `{"partialUpdate": [
+		{"documentId": "product://a", "operator": "fieldValueReplace", "field": "ec_price", "value": 39.99},
+		{"documentId": "product://a", "operator": "arrayAppend", "field": "ec_images", "value": ["https://img/a2.png"]},
+		{"documentId": "store://1", "operator": "arrayRemove", "field": "ec_available_items", "value": ["a1", "a2"]}
+	]}`, string(server.uploads["/upload/file1"]))
+
+	invalid := pushapi.CatalogUpdate{}
+	invalid.AddPartialUpdates(pushapi.NewPartialUpdates("product://a").Replace("", 1))
+	if err := server.catalogClient(t).Update("mysource", invalid, pushapi.StreamOptions{}); err == nil {
+		t.Errorf("expected an error for a partial update without field")
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/coveo/go-coveo/auth"
+	"github.com/coveo/go-coveo/endpoint"
+	"github.com/coveo/go-coveo/internal/httpapi"
 )
 
 const (
@@ -18,39 +23,94 @@ const (
 type Client interface {
 	PushDocument(d Document, sourceID string) (string, error)
 	DeleteDocument(documentID string, sourceID string) error
+	DeleteDocumentWithOptions(documentID string, sourceID string, o DeleteOptions) error
+	DeleteOlderThan(sourceID string, orderingID int64) error
+	BatchPush(b Batch, sourceID string) error
+	SetSourceStatus(sourceID string, status SourceStatus) error
 	PushIdentity(i Identity, providerID string) error
 	DeleteIdentity(i Identity, providerID string) error
+	PushIdentityBatch(b IdentityBatch, providerID string, o BatchOptions) error
+	DeleteIdentitiesOlderThan(providerID string, orderingID int64) error
+	RefreshSecurityProvider(providerID string) error
 }
 
 // Config is used to configure a new client
 type Config struct {
 	// Endpoint is used if you want to use custom endpoints (dev,staging,testing)
 	Endpoint string
+	// PlatformEndpoint is the platform API endpoint, resolved from the region
+	// and environment if empty. It is required by RefreshSecurityProvider
+	// with a custom Endpoint.
+	PlatformEndpoint string
 	// The Coveo organization ID
 	OrganizationID string
 	// APIKey is the key used to push content to Coveo
 	APIKey string
+	// TokenSource provides the key of every request, APIKey is used if nil
+	TokenSource auth.TokenSource
+	// Region is the region of the organization, used when Endpoint is empty
+	Region endpoint.Region
+	// Environment is the environment of the organization, used when Endpoint
+	// is empty
+	Environment endpoint.Environment
+	// AutoOrderingID generates an ordering ID with NewOrderingID for every
+	// document or identity operation sent without one
+	AutoOrderingID bool
+	// Validate checks the documents with Validate before sending them, and
+	// returns the ValidationErrors instead of sending invalid documents
+	Validate bool
 }
 
-// NewClient initializes a new pushapi client with the config param
+// NewClient initializes a new pushapi client with the config param. The
+// PlatformEndpoint is not resolved with a custom Endpoint and no region or
+// environment, RefreshSecurityProvider then needs a custom PlatformEndpoint.
 func NewClient(c Config) (Client, error) {
+	customEndpoint := len(c.Endpoint) != 0 && c.Endpoint != EndpointProduction
+	resolvePlatform := !customEndpoint || len(c.Region) != 0 || len(c.Environment) != 0
+
 	if len(c.Endpoint) == 0 {
-		c.Endpoint = EndpointProduction
+		if len(c.Region) == 0 && len(c.Environment) == 0 {
+			c.Endpoint = EndpointProduction
+		} else {
+			resolved, err := endpoint.Resolve(endpoint.Push, c.Environment, c.Region)
+			if err != nil {
+				return nil, err
+			}
+			c.Endpoint = resolved
+		}
+	}
+
+	if len(c.PlatformEndpoint) == 0 && resolvePlatform {
+		resolved, err := endpoint.Resolve(endpoint.Platform, c.Environment, c.Region)
+		if err != nil {
+			return nil, err
+		}
+		c.PlatformEndpoint = resolved
+	}
+
+	if c.TokenSource == nil {
+		c.TokenSource = auth.StaticToken(c.APIKey)
 	}
 
 	return &client{
-		apikey:         c.APIKey,
-		endpoint:       c.Endpoint,
-		organizationid: c.OrganizationID,
-		httpClient:     http.DefaultClient,
+		endpoint:         c.Endpoint,
+		platformEndpoint: c.PlatformEndpoint,
+		organizationid:   c.OrganizationID,
+		httpClient:       auth.NewHTTPClient(c.TokenSource),
+		uploadClient:     http.DefaultClient,
+		autoOrderingID:   c.AutoOrderingID,
+		validate:         c.Validate,
 	}, nil
 }
 
 type client struct {
-	httpClient     *http.Client
-	apikey         string
-	endpoint       string
-	organizationid string
+	httpClient       *http.Client
+	uploadClient     *http.Client
+	endpoint         string
+	platformEndpoint string
+	organizationid   string
+	autoOrderingID   bool
+	validate         bool
 }
 
 // PushDocument will send a document to the pushapi in the specified source
@@ -63,16 +123,30 @@ func (c *client) PushDocument(d Document, sourceID string) (string, error) {
 		return "", errors.New("You need to provide a documentID")
 	}
 
-	marshalledDocument, err := json.Marshal(d.Fields)
+	if c.validate {
+		if err := Validate(d); err != nil {
+			return "", err
+		}
+	}
+
+	marshalledDocument, err := json.Marshal(d.body())
 	if err != nil {
 		return "", err
 	}
+
+	if c.validate {
+		if err := validatePushDocumentSize(len(marshalledDocument)); err != nil {
+			return "", err
+		}
+	}
 	buf := bytes.NewReader(marshalledDocument)
 
-	endpoint := fmt.Sprintf("%s%s/sources/%s/documents?documentId=%s",
-		c.endpoint, c.organizationid, sourceID, d.DocumentID)
+	endpoint := c.documentsEndpoint(sourceID, d.DocumentID, d.OrderingID, false)
 
 	req, err := http.NewRequest("PUT", endpoint, buf)
+	if err != nil {
+		return "", err
+	}
 	resp, err := c.sendRequest(req)
 	if err != nil {
 		return "", err
@@ -83,6 +157,22 @@ func (c *client) PushDocument(d Document, sourceID string) (string, error) {
 
 // DeleteDocument will send a delete request for the specified documentID in the sourceID
 func (c *client) DeleteDocument(documentID, sourceID string) error {
+	return c.DeleteDocumentWithOptions(documentID, sourceID, DeleteOptions{})
+}
+
+// DeleteOptions are the options of a document deletion
+type DeleteOptions struct {
+	// OrderingID is the ordering ID of the deletion, generated if zero and
+	// AutoOrderingID is set
+	OrderingID int64
+	// DeleteChildren also deletes the documents having the deleted document
+	// as parent, recursively
+	DeleteChildren bool
+}
+
+// DeleteDocumentWithOptions will send a delete request for the specified
+// documentID in the sourceID
+func (c *client) DeleteDocumentWithOptions(documentID, sourceID string, o DeleteOptions) error {
 	if len(sourceID) == 0 {
 		return errors.New("You need a sourceID")
 	}
@@ -91,10 +181,12 @@ func (c *client) DeleteDocument(documentID, sourceID string) error {
 		return errors.New("You need a documentID")
 	}
 
-	endpoint := fmt.Sprintf("%s%s/sources/%s/documents?documentId=%s",
-		c.endpoint, c.organizationid, sourceID, documentID)
+	endpoint := c.documentsEndpoint(sourceID, documentID, o.OrderingID, o.DeleteChildren)
 
 	req, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
 	_, err = c.sendRequest(req)
 	if err != nil {
 		return err
@@ -103,34 +195,120 @@ func (c *client) DeleteDocument(documentID, sourceID string) error {
 	return nil
 }
 
+// DeleteOlderThan deletes the documents of the source that were last updated
+// with an ordering ID lower than orderingID
+func (c *client) DeleteOlderThan(sourceID string, orderingID int64) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	endpoint := fmt.Sprintf("%s%s/sources/%s/documents/olderthan?orderingId=%d",
+		c.endpoint, c.organizationid, url.PathEscape(sourceID), orderingID)
+
+	req, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
+}
+
+// documentsEndpoint returns the URL of a single document operation
+func (c *client) documentsEndpoint(sourceID, documentID string, orderingID int64, deleteChildren bool) string {
+	query := url.Values{}
+	query.Set("documentId", documentID)
+	if orderingID = c.orderingID(orderingID); orderingID != 0 {
+		query.Set("orderingId", strconv.FormatInt(orderingID, 10))
+	}
+	if deleteChildren {
+		query.Set("deleteChildren", "true")
+	}
+
+	return fmt.Sprintf("%s%s/sources/%s/documents?%s",
+		c.endpoint, c.organizationid, url.PathEscape(sourceID), query.Encode())
+}
+
+// orderingID returns the ordering ID to send for an operation, generating
+// one if none is given and the client generates them
+func (c *client) orderingID(orderingID int64) int64 {
+	if orderingID == 0 && c.autoOrderingID {
+		return NewOrderingID()
+	}
+	return orderingID
+}
+
+// PushIdentity will add or update the identity in the security provider
 func (c *client) PushIdentity(i Identity, providerID string) error {
-	// TODO: Implement this method
-	return nil
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	if err := i.Validate(); err != nil {
+		return err
+	}
+
+	marshalledIdentity, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", c.identityEndpoint(providerID, i.OrderingID), bytes.NewReader(marshalledIdentity))
+	if err != nil {
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
 }
 
+// DeleteIdentity will delete the identity from the security provider, only
+// the Identity field of i is used
 func (c *client) DeleteIdentity(i Identity, providerID string) error {
-	// TODO: Implement this method
-	return nil
-}
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
 
-func (c *client) sendRequest(req *http.Request) (string, error) {
-	req.Header.Add("Authorization", "Bearer "+c.apikey)
-	req.Header.Add("Content-Type", "application/json")
+	if errs := i.Identity.validate("identity"); len(errs) != 0 {
+		return errs
+	}
 
-	resp, err := c.httpClient.Do(req)
+	marshalledIdentity, err := json.Marshal(Identity{Identity: i.Identity})
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	req, err := http.NewRequest("DELETE", c.identityEndpoint(providerID, i.OrderingID), bytes.NewReader(marshalledIdentity))
 	if err != nil {
-		return "", err
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
+}
+
+// validatePushDocumentSize returns ValidationErrors if a document payload of
+// this size is too large for PushDocument
+func validatePushDocumentSize(size int) error {
+	if size > MaxPushDocumentSize {
+		return ValidationErrors{{
+			Message: fmt.Sprintf("the document is %d bytes, use BatchPush for documents larger than %d bytes", size, MaxPushDocumentSize),
+		}}
 	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusAccepted {
-		return "", errors.New(string(body))
+func (c *client) permissionsEndpoint(providerID string) string {
+	return fmt.Sprintf("%s%s/providers/%s/permissions",
+		c.endpoint, c.organizationid, url.PathEscape(providerID))
+}
+
+// identityEndpoint returns the URL of a single identity operation
+func (c *client) identityEndpoint(providerID string, orderingID int64) string {
+	if orderingID = c.orderingID(orderingID); orderingID != 0 {
+		return fmt.Sprintf("%s?orderingId=%d", c.permissionsEndpoint(providerID), orderingID)
 	}
+	return c.permissionsEndpoint(providerID)
+}
 
-	return string(body), nil
+func (c *client) sendRequest(req *http.Request) (string, error) {
+	body, err := httpapi.Send(c.httpClient, req)
+	return string(body), err
 }
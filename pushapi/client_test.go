@@ -0,0 +1,36 @@
+package pushapi_test
+
+import (
+	"testing"
+
+	"github.com/coveo/go-coveo/endpoint"
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestNewClientEndpoints(t *testing.T) {
+	configs := []pushapi.Config{
+		{},
+		{Endpoint: pushapi.EndpointProduction},
+		{Region: endpoint.RegionEU},
+		{Endpoint: "https://push.example.com/", Environment: endpoint.Staging},
+		{Endpoint: "https://push.example.com/", PlatformEndpoint: "https://platform.example.com/"},
+		{Endpoint: "https://push.example.com/"},
+		{PlatformEndpoint: "https://platform.example.com/"},
+	}
+	for _, config := range configs {
+		if _, err := pushapi.NewClient(config); err != nil {
+			t.Errorf("unexpected error for %+v.  expected %v, actual %v", config, nil, err)
+		}
+	}
+}
+
+func TestRefreshSecurityProviderNeedsPlatformEndpoint(t *testing.T) {
+	// The platform endpoint of a custom push endpoint is unknown
+	c, err := pushapi.NewClient(pushapi.Config{Endpoint: "https://push.example.com/", OrganizationID: "myorg"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := c.RefreshSecurityProvider("myprovider"); err == nil {
+		t.Error("expected an error without a PlatformEndpoint")
+	}
+}
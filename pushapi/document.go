@@ -1,7 +1,243 @@
 package pushapi
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateFormat is the format of the dates sent to the Push API
+const DateFormat = time.RFC3339
+
+// CompressionType is the algorithm used to compress the binary data of a
+// document
+type CompressionType string
+
+const (
+	// CompressionUncompressed means the binary data is not compressed
+	CompressionUncompressed CompressionType = "UNCOMPRESSED"
+	// CompressionDeflate means the binary data is compressed with DEFLATE
+	CompressionDeflate CompressionType = "DEFLATE"
+	// CompressionGzip means the binary data is compressed with GZIP
+	CompressionGzip CompressionType = "GZIP"
+	// CompressionZlib means the binary data is compressed with ZLIB
+	CompressionZlib CompressionType = "ZLIB"
+)
+
+// Keys of the document body with a special meaning for the Push API, they
+// are set from the typed fields of Document rather than from Fields.
+const (
+	keyDocumentID           = "documentId"
+	keyTitle                = "title"
+	keyClickableURI         = "clickableUri"
+	keyAuthor               = "author"
+	keyDate                 = "date"
+	keyModifiedDate         = "modifiedDate"
+	keyFileExtension        = "fileExtension"
+	keyData                 = "data"
+	keyCompressedBinaryData = "compressedBinaryData"
+	keyCompressionType      = "compressionType"
+	keyParentID             = "parentId"
+	keyPermissions          = "permissions"
+)
+
 // Document represents the structure of a document
 type Document struct {
-	DocumentID string                 `json:"documentId"`
-	Fields     map[string]interface{} `json:"body"`
+	// DocumentID is the unique ID of the document, it must be a valid URI
+	DocumentID string
+	// Title is the title of the document
+	Title string
+	// ClickableURI is the URI opened when the document is clicked in a search
+	// page, DocumentID if empty
+	ClickableURI string
+	// Author is the author of the document
+	Author string
+	// Date is the date of the document
+	Date time.Time
+	// ModifiedDate is the last modification date of the document
+	ModifiedDate time.Time
+	// FileExtension is the extension of the original file, with the dot
+	FileExtension string
+	// Data is the textual content of the document
+	Data string
+	// CompressedBinaryData is the base64 encoded content of the original
+	// file, compressed with CompressionType
+	CompressedBinaryData string
+	// CompressionType is the compression of CompressedBinaryData
+	CompressionType CompressionType
+	// ParentID is the DocumentID of the parent of the document
+	ParentID string
+	// Permissions are the permission levels of the document, from the most to
+	// the least important. A document without permissions is public.
+	Permissions []PermissionLevel
+	// Fields holds the metadata of the document, keyed by field name
+	Fields map[string]interface{}
+	// OrderingID is the ordering ID sent with the document, it is not part of
+	// its body. It is generated if zero and AutoOrderingID is set.
+	OrderingID int64
+}
+
+// PermissionIdentityType is the type of an identity in the permissions of a
+// document
+type PermissionIdentityType string
+
+const (
+	// PermissionIdentityUser is a user identity
+	PermissionIdentityUser PermissionIdentityType = "User"
+	// PermissionIdentityGroup is a group identity
+	PermissionIdentityGroup PermissionIdentityType = "Group"
+	// PermissionIdentityVirtualGroup is a virtual group identity
+	PermissionIdentityVirtualGroup PermissionIdentityType = "VirtualGroup"
+	// PermissionIdentityUnknown is an identity of an unknown type
+	PermissionIdentityUnknown PermissionIdentityType = "Unknown"
+)
+
+// PermissionLevel is a set of permission sets. An identity is allowed to see
+// a document if it is allowed by one of the sets of every level, and denied
+// by none of them.
+type PermissionLevel struct {
+	Name           string          `json:"name,omitempty"`
+	PermissionSets []PermissionSet `json:"permissionSets"`
+}
+
+// PermissionSet lists the identities allowed and denied to see a document
+type PermissionSet struct {
+	Name               string       `json:"name,omitempty"`
+	AllowAnonymous     bool         `json:"allowAnonymous"`
+	AllowedPermissions []Permission `json:"allowedPermissions,omitempty"`
+	DeniedPermissions  []Permission `json:"deniedPermissions,omitempty"`
+}
+
+// Permission identifies an identity of a security provider
+type Permission struct {
+	Identity         string                 `json:"identity"`
+	IdentityType     PermissionIdentityType `json:"identityType"`
+	SecurityProvider string                 `json:"securityProvider,omitempty"`
+	AdditionalInfo   map[string]string      `json:"additionalInfo,omitempty"`
+}
+
+// SimplePermissions returns the permissions of a document with a single
+// permission set
+func SimplePermissions(allowAnonymous bool, allowed []Permission, denied []Permission) []PermissionLevel {
+	return []PermissionLevel{{
+		PermissionSets: []PermissionSet{{
+			AllowAnonymous:     allowAnonymous,
+			AllowedPermissions: allowed,
+			DeniedPermissions:  denied,
+		}},
+	}}
+}
+
+// MarshalJSON serializes the document as expected by the Push API, the
+// metadata next to the reserved keys. The typed fields take precedence over
+// metadata with the same name.
+func (d Document) MarshalJSON() ([]byte, error) {
+	body := d.body()
+	body[keyDocumentID] = d.DocumentID
+	return json.Marshal(body)
+}
+
+// UnmarshalJSON reads a document serialized by MarshalJSON
+func (d *Document) UnmarshalJSON(data []byte) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	doc := Document{}
+	var dateValue, modifiedDateValue string
+	targets := map[string]interface{}{
+		keyDocumentID:           &doc.DocumentID,
+		keyTitle:                &doc.Title,
+		keyClickableURI:         &doc.ClickableURI,
+		keyAuthor:               &doc.Author,
+		keyDate:                 &dateValue,
+		keyModifiedDate:         &modifiedDateValue,
+		keyFileExtension:        &doc.FileExtension,
+		keyData:                 &doc.Data,
+		keyCompressedBinaryData: &doc.CompressedBinaryData,
+		keyCompressionType:      &doc.CompressionType,
+		keyParentID:             &doc.ParentID,
+		keyPermissions:          &doc.Permissions,
+	}
+
+	for key, value := range raw {
+		target, ok := targets[key]
+		if !ok {
+			var field interface{}
+			if err := json.Unmarshal(value, &field); err != nil {
+				return err
+			}
+			if doc.Fields == nil {
+				doc.Fields = map[string]interface{}{}
+			}
+			doc.Fields[key] = field
+			continue
+		}
+
+		if err := json.Unmarshal(value, target); err != nil {
+			return fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+
+	var err error
+	if doc.Date, err = parseDate(keyDate, dateValue); err != nil {
+		return err
+	}
+	if doc.ModifiedDate, err = parseDate(keyModifiedDate, modifiedDateValue); err != nil {
+		return err
+	}
+
+	*d = doc
+	return nil
+}
+
+// body returns the content sent for the document, without its ID
+func (d Document) body() map[string]interface{} {
+	body := make(map[string]interface{}, len(d.Fields)+12)
+	for key, value := range d.Fields {
+		body[key] = value
+	}
+
+	setString := func(key, value string) {
+		if len(value) != 0 {
+			body[key] = value
+		}
+	}
+	setString(keyTitle, d.Title)
+	setString(keyClickableURI, d.ClickableURI)
+	setString(keyAuthor, d.Author)
+	setString(keyFileExtension, d.FileExtension)
+	setString(keyData, d.Data)
+	setString(keyCompressedBinaryData, d.CompressedBinaryData)
+	setString(keyParentID, d.ParentID)
+	if len(d.CompressedBinaryData) != 0 {
+		compressionType := d.CompressionType
+		if len(compressionType) == 0 {
+			compressionType = CompressionUncompressed
+		}
+		body[keyCompressionType] = compressionType
+	}
+	if !d.Date.IsZero() {
+		body[keyDate] = d.Date.Format(DateFormat)
+	}
+	if !d.ModifiedDate.IsZero() {
+		body[keyModifiedDate] = d.ModifiedDate.Format(DateFormat)
+	}
+	if len(d.Permissions) != 0 {
+		body[keyPermissions] = d.Permissions
+	}
+
+	return body
+}
+
+func parseDate(key, value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(DateFormat, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return date, nil
 }
@@ -0,0 +1,62 @@
+package pushapi_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestDocumentMarshalJSON(t *testing.T) {
+	d := pushapi.Document{
+		DocumentID:   "file://folder/doc.txt",
+		Title:        "My document",
+		Date:         time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC),
+		Data:         "Hello",
+		Fields:       map[string]interface{}{"myfield": "value"},
+		Permissions:  pushapi.SimplePermissions(false, []pushapi.Permission{{Identity: "bob@example.com", IdentityType: pushapi.PermissionIdentityUser}}, nil),
+		ClickableURI: "https://example.com/doc.txt",
+	}
+
+	actual, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expected := `{
+		"documentId": "file://folder/doc.txt",
+		"title": "My document",
+		"clickableUri": "https://example.com/doc.txt",
+		"date": "2017-03-04T05:06:07Z",
+		"data": "Hello",
+		"myfield": "value",
+		"permissions": [{"permissionSets": [{
+			"allowAnonymous": false,
+			"allowedPermissions": [{"identity": "bob@example.com", "identityType": "User"}]
+		}]}]
+	}`
+	assertJSONEqual(t, expected, string(actual))
+
+	roundTrip := pushapi.Document{}
+	if err := json.Unmarshal(actual, &roundTrip); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if !reflect.DeepEqual(d, roundTrip) {
+		t.Fatalf("unexpected document.  expected %+v, actual %+v", d, roundTrip)
+	}
+}
+
+func assertJSONEqual(t *testing.T, expected, actual string) {
+	var e, a interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		t.Fatalf("invalid actual JSON: %v", err)
+	}
+	if !reflect.DeepEqual(e, a) {
+		t.Fatalf("unexpected JSON.  expected %s, actual %s", expected, actual)
+	}
+}
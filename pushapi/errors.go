@@ -0,0 +1,39 @@
+package pushapi
+
+import (
+	"strings"
+
+	"github.com/coveo/go-coveo/internal/httpapi"
+)
+
+// ValidationError is a problem found in a value before it is sent
+type ValidationError struct {
+	// Field is the path of the invalid field, empty if the problem is with
+	// the value as a whole
+	Field string `json:"field"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Field) == 0 {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors are all the problems found in a value
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// APIError is returned when the Push API, or the platform API, does not
+// accept a request. It has the StatusCode of the response, and the ErrorCode
+// and Message of its body if any.
+type APIError = httpapi.APIError
@@ -0,0 +1,9 @@
+package pushapi
+
+import "io"
+
+// SetBinaryDataWithLimit is SetBinaryData with a smaller size limit than
+// MaxCompressedBinaryDataSize
+func (d *Document) SetBinaryDataWithLimit(r io.Reader, compression CompressionType, limit int) error {
+	return d.setBinaryData(r, compression, limit)
+}
@@ -0,0 +1,85 @@
+package pushapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/coveo/go-coveo/internal/httpapi"
+)
+
+// fileContainer is a temporary storage where large payloads are uploaded
+// before being referenced by a batch request
+type fileContainer struct {
+	UploadURI       string            `json:"uploadUri"`
+	FileID          string            `json:"fileId"`
+	RequiredHeaders map[string]string `json:"requiredHeaders"`
+}
+
+// createFileContainer asks the Push API for a new file container
+func (c *client) createFileContainer() (*fileContainer, error) {
+	endpoint := fmt.Sprintf("%s%s/files", c.endpoint, c.organizationid)
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	container := &fileContainer{}
+	if err := json.Unmarshal([]byte(resp), container); err != nil {
+		return nil, err
+	}
+	return container, nil
+}
+
+// upload sends the payload to the upload URI of the file container. The
+// upload URI is pre-signed, so the request is sent without the API key.
+func (c *client) upload(container *fileContainer, payload []byte) error {
+	req, err := http.NewRequest("PUT", container.UploadURI, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for name, value := range container.RequiredHeaders {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.uploadClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return httpapi.NewAPIError(resp.StatusCode, body)
+	}
+	return nil
+}
+
+// uploadPayload marshals v and uploads it to a new file container, returning
+// the ID of the file
+func (c *client) uploadPayload(v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	container, err := c.createFileContainer()
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.upload(container, payload); err != nil {
+		return "", err
+	}
+	return container.FileID, nil
+}
@@ -0,0 +1,69 @@
+package pushapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestFileContainerUploadFailure(t *testing.T) {
+	requests := []string{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/myorg/files":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"uploadUri": server.URL + "/upload/file1",
+				"fileId":    "file1",
+			})
+		case "/upload/file1":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("expired upload URI"))
+		}
+	}))
+	defer server.Close()
+
+	c, err := pushapi.NewClient(pushapi.Config{
+		Endpoint:         server.URL + "/",
+		PlatformEndpoint: server.URL + "/platform/",
+		OrganizationID:   "myorg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	err = c.BatchPush(pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: "file://a"}}}, "mysource")
+	apiErr, ok := err.(*pushapi.APIError)
+	if !ok || apiErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("unexpected error.  expected a %v APIError, actual %v", http.StatusForbidden, err)
+	}
+	// The batch is not applied when the upload fails
+	if len(requests) != 2 {
+		t.Errorf("unexpected requests.  expected %v, actual %v", 2, requests)
+	}
+}
+
+func TestFileContainerCreationFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c, err := pushapi.NewClient(pushapi.Config{
+		Endpoint:         server.URL + "/",
+		PlatformEndpoint: server.URL + "/platform/",
+		OrganizationID:   "myorg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	err = c.BatchPush(pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: "file://a"}}}, "mysource")
+	if apiErr, ok := err.(*pushapi.APIError); !ok || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("unexpected error.  expected a %v APIError, actual %v", http.StatusTooManyRequests, err)
+	}
+}
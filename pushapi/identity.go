@@ -1,6 +1,89 @@
 package pushapi
 
-// Identity represents the data structure of a Coveo identity
+import "fmt"
+
+// IdentityType is the type of a security identity
+type IdentityType string
+
+const (
+	// IdentityTypeUser is a user identity
+	IdentityTypeUser IdentityType = "USER"
+	// IdentityTypeGroup is a group of identities
+	IdentityTypeGroup IdentityType = "GROUP"
+	// IdentityTypeVirtualGroup is a group of identities that only exists in Coveo
+	IdentityTypeVirtualGroup IdentityType = "VIRTUAL_GROUP"
+	// IdentityTypeUnknown is an identity of an unknown type
+	IdentityTypeUnknown IdentityType = "UNKNOWN"
+)
+
+// PermissionIdentity identifies a security identity of a security provider
+type PermissionIdentity struct {
+	// Name is the name of the identity, unique within its security provider
+	Name string `json:"name"`
+	// Type is the type of the identity
+	Type IdentityType `json:"type"`
+	// AdditionalInfo holds additional information used to identify the identity
+	AdditionalInfo map[string]string `json:"additionalInfo,omitempty"`
+}
+
+// Identity represents the data structure of a Coveo identity, with the
+// identities it is related to
 type Identity struct {
-	// TODO: Complete Identity struct when the code is ready
+	// Identity is the identity pushed or deleted
+	Identity PermissionIdentity `json:"identity"`
+	// Members are the identities part of a group or virtual group
+	Members []PermissionIdentity `json:"members,omitempty"`
+	// Mappings are the identities of other security providers the identity
+	// is mapped to
+	Mappings []PermissionIdentity `json:"mappings,omitempty"`
+	// WellKnowns are the well known identities the identity is a member of
+	WellKnowns []PermissionIdentity `json:"wellKnowns,omitempty"`
+	// OrderingID is the ordering ID sent with the identity, it is not part of
+	// its body. It is generated if zero and AutoOrderingID is set, and
+	// ignored in an IdentityBatch, see BatchOptions.
+	OrderingID int64 `json:"-"`
+}
+
+// Validate checks that the identity and its related identities have a name
+// and a valid type, and that only groups have members.
+func (i Identity) Validate() error {
+	errs := ValidationErrors{}
+	errs = append(errs, i.Identity.validate("identity")...)
+
+	isGroup := i.Identity.Type == IdentityTypeGroup || i.Identity.Type == IdentityTypeVirtualGroup
+	if len(i.Members) != 0 && !isGroup {
+		errs = append(errs, &ValidationError{
+			Field:   "members",
+			Message: fmt.Sprintf("only identities of type %s or %s can have members", IdentityTypeGroup, IdentityTypeVirtualGroup),
+		})
+	}
+
+	for index, member := range i.Members {
+		errs = append(errs, member.validate(fmt.Sprintf("members[%d]", index))...)
+	}
+	for index, mapping := range i.Mappings {
+		errs = append(errs, mapping.validate(fmt.Sprintf("mappings[%d]", index))...)
+	}
+	for index, wellKnown := range i.WellKnowns {
+		errs = append(errs, wellKnown.validate(fmt.Sprintf("wellKnowns[%d]", index))...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (p PermissionIdentity) validate(field string) ValidationErrors {
+	errs := ValidationErrors{}
+	if len(p.Name) == 0 {
+		errs = append(errs, &ValidationError{Field: field + ".name", Message: "the name is required"})
+	}
+
+	switch p.Type {
+	case IdentityTypeUser, IdentityTypeGroup, IdentityTypeVirtualGroup, IdentityTypeUnknown:
+	default:
+		errs = append(errs, &ValidationError{Field: field + ".type", Message: fmt.Sprintf("invalid identity type %q", p.Type)})
+	}
+	return errs
 }
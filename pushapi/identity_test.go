@@ -0,0 +1,136 @@
+package pushapi_test
+
+import (
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestIdentityValidate(t *testing.T) {
+	group := pushapi.PermissionIdentity{Name: "admins", Type: pushapi.IdentityTypeGroup}
+	alice := pushapi.PermissionIdentity{Name: "alice@example.com", Type: pushapi.IdentityTypeUser}
+
+	tests := []struct {
+		name     string
+		identity pushapi.Identity
+		fields   []string
+	}{
+		{"user", pushapi.Identity{Identity: alice}, nil},
+		{"group with members", pushapi.Identity{Identity: group, Members: []pushapi.PermissionIdentity{alice}}, nil},
+		{"user with mappings", pushapi.Identity{Identity: alice, Mappings: []pushapi.PermissionIdentity{{Name: "alice", Type: pushapi.IdentityTypeUnknown}}}, nil},
+		{"no name", pushapi.Identity{Identity: pushapi.PermissionIdentity{Type: pushapi.IdentityTypeUser}}, []string{"identity.name"}},
+		{"no type", pushapi.Identity{Identity: pushapi.PermissionIdentity{Name: "alice"}}, []string{"identity.type"}},
+		{"user with members", pushapi.Identity{Identity: alice, Members: []pushapi.PermissionIdentity{group}}, []string{"members"}},
+		{
+			"invalid related identities",
+			pushapi.Identity{
+				Identity:   group,
+				Members:    []pushapi.PermissionIdentity{alice, {Type: pushapi.IdentityTypeUser}},
+				Mappings:   []pushapi.PermissionIdentity{{Name: "alice", Type: "ROBOT"}},
+				WellKnowns: []pushapi.PermissionIdentity{{Name: "everyone"}},
+			},
+			[]string{"members[1].name", "mappings[0].type", "wellKnowns[0].type"},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.identity.Validate()
+		if len(test.fields) == 0 {
+			if err != nil {
+				t.Errorf("unexpected error for %s.  expected %v, actual %v", test.name, nil, err)
+			}
+			continue
+		}
+
+		errs, ok := err.(pushapi.ValidationErrors)
+		if !ok {
+			t.Errorf("unexpected error for %s.  expected ValidationErrors, actual %v", test.name, err)
+			continue
+		}
+		if len(errs) != len(test.fields) {
+			t.Errorf("unexpected errors for %s.  expected %v, actual %v", test.name, test.fields, errs)
+			continue
+		}
+		for i, field := range test.fields {
+			if errs[i].Field != field {
+				t.Errorf("unexpected field for %s.  expected %v, actual %v", test.name, field, errs[i].Field)
+			}
+		}
+	}
+}
+
+func TestValidationErrorsMessage(t *testing.T) {
+	errs := pushapi.ValidationErrors{
+		{Field: "identity.name", Message: "the name is required"},
+		{Message: "the document is too large"},
+	}
+	expected := "identity.name: the name is required; the document is too large"
+	if errs.Error() != expected {
+		t.Errorf("unexpected message.  expected %v, actual %v", expected, errs.Error())
+	}
+}
+
+func TestPushIdentity(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	identity := pushapi.Identity{
+		Identity: pushapi.PermissionIdentity{Name: "admins", Type: pushapi.IdentityTypeGroup},
+		Members:  []pushapi.PermissionIdentity{{Name: "alice@example.com", Type: pushapi.IdentityTypeUser}},
+	}
+	if err := server.client(t).PushIdentity(identity, "my provider"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expected := "PUT /myorg/providers/my%20provider/permissions"
+	if len(server.requests) != 1 || server.requests[0] != expected {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+	assertJSONEqual(t, `{
+		"identity": {"name": "admins", "type": "GROUP"},
+		"members": [{"name": "alice@example.com", "type": "USER"}]
+	}`, server.bodies[0])
+}
+
+func TestDeleteIdentity(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	// Only the identity itself is sent
+	identity := pushapi.Identity{
+		Identity: pushapi.PermissionIdentity{Name: "admins", Type: pushapi.IdentityTypeGroup},
+		Members:  []pushapi.PermissionIdentity{{Name: "alice@example.com", Type: pushapi.IdentityTypeUser}},
+	}
+	if err := server.client(t).DeleteIdentity(identity, "myprovider"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expected := "DELETE /myorg/providers/myprovider/permissions"
+	if len(server.requests) != 1 || server.requests[0] != expected {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+	assertJSONEqual(t, `{"identity": {"name": "admins", "type": "GROUP"}}`, server.bodies[0])
+}
+
+func TestPushIdentityInvalid(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+	c := server.client(t)
+
+	valid := pushapi.Identity{Identity: pushapi.PermissionIdentity{Name: "alice", Type: pushapi.IdentityTypeUser}}
+	if err := c.PushIdentity(valid, ""); err == nil {
+		t.Errorf("expected an error without providerID")
+	}
+	if err := c.DeleteIdentity(valid, ""); err == nil {
+		t.Errorf("expected an error without providerID")
+	}
+	if err := c.PushIdentity(pushapi.Identity{}, "myprovider"); err == nil {
+		t.Errorf("expected an error for an invalid identity")
+	}
+	if err := c.DeleteIdentity(pushapi.Identity{}, "myprovider"); err == nil {
+		t.Errorf("expected an error for an invalid identity")
+	}
+	if len(server.requests) != 0 {
+		t.Errorf("unexpected requests.  expected none, actual %v", server.requests)
+	}
+}
@@ -0,0 +1,189 @@
+package pushapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DefaultMaxBatchItems is the number of items sent per file container when
+// BatchOptions.MaxItems is zero
+const DefaultMaxBatchItems = 5000
+
+// IdentityBatch is a set of identity updates sent in a single request
+type IdentityBatch struct {
+	// Members are the identities to add or update, with their members
+	Members []Identity `json:"members,omitempty"`
+	// Mappings are the identities to add or update, with their mappings
+	Mappings []Identity `json:"mappings,omitempty"`
+	// Deleted are the identities to delete
+	Deleted []Identity `json:"deleted,omitempty"`
+}
+
+// Validate checks every identity of the batch, see Identity.Validate
+func (b IdentityBatch) Validate() error {
+	errs := ValidationErrors{}
+	lists := []struct {
+		field      string
+		identities []Identity
+	}{{"members", b.Members}, {"mappings", b.Mappings}, {"deleted", b.Deleted}}
+	for _, list := range lists {
+		for index, i := range list.identities {
+			if err := i.Validate(); err != nil {
+				for _, e := range err.(ValidationErrors) {
+					errs = append(errs, &ValidationError{
+						Field:   fmt.Sprintf("%s[%d].%s", list.field, index, e.Field),
+						Message: e.Message,
+					})
+				}
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+func (b IdentityBatch) len() int {
+	return len(b.Members) + len(b.Mappings) + len(b.Deleted)
+}
+
+// BatchOptions controls how a batch is split and reports its progress
+type BatchOptions struct {
+	// MaxItems is the maximum number of items sent per file container,
+	// DefaultMaxBatchItems if zero
+	MaxItems int
+	// OrderingID is the ordering ID of every identity of the batch,
+	// generated if zero and AutoOrderingID is set
+	OrderingID int64
+	// Progress is called after each file container is sent
+	Progress func(BatchProgress)
+}
+
+// BatchProgress reports the state of a batch after each file container
+type BatchProgress struct {
+	// Sent is the number of items sent so far
+	Sent int
+	// Total is the number of items of the batch
+	Total int
+	// Containers is the number of file containers sent so far
+	Containers int
+}
+
+// PushIdentityBatch validates every identity of the batch, then uploads them
+// to file containers holding at most MaxItems identities each and applies
+// them to the security provider.
+func (c *client) PushIdentityBatch(b IdentityBatch, providerID string, o BatchOptions) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	if err := b.Validate(); err != nil {
+		return err
+	}
+
+	maxItems := o.MaxItems
+	if maxItems <= 0 {
+		maxItems = DefaultMaxBatchItems
+	}
+
+	orderingID := c.orderingID(o.OrderingID)
+	progress := BatchProgress{Total: b.len()}
+	for _, chunk := range splitIdentityBatch(b, maxItems) {
+		fileID, err := c.uploadPayload(chunk)
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		query.Set("fileId", fileID)
+		if orderingID != 0 {
+			query.Set("orderingId", strconv.FormatInt(orderingID, 10))
+		}
+		endpoint := fmt.Sprintf("%s/batch?%s", c.permissionsEndpoint(providerID), query.Encode())
+		req, err := http.NewRequest("PUT", endpoint, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := c.sendRequest(req); err != nil {
+			return err
+		}
+
+		progress.Sent += chunk.len()
+		progress.Containers++
+		if o.Progress != nil {
+			o.Progress(progress)
+		}
+	}
+	return nil
+}
+
+// DeleteIdentitiesOlderThan deletes the identities of the security provider
+// that were last updated with an ordering ID lower than orderingID
+func (c *client) DeleteIdentitiesOlderThan(providerID string, orderingID int64) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	endpoint := fmt.Sprintf("%s/olderthan?orderingId=%s",
+		c.permissionsEndpoint(providerID), strconv.FormatInt(orderingID, 10))
+	req, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
+}
+
+// RefreshSecurityProvider asks the platform to refresh the security provider,
+// so that the permissions of the documents reflect the latest identities
+func (c *client) RefreshSecurityProvider(providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	if len(c.platformEndpoint) == 0 {
+		return errors.New("You need a PlatformEndpoint with a custom Endpoint")
+	}
+
+	endpoint := fmt.Sprintf("%s%s/securityproviders/%s/refresh",
+		c.platformEndpoint, c.organizationid, url.PathEscape(providerID))
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
+}
+
+// splitIdentityBatch splits the batch in batches of at most maxItems
+// identities, keeping the members first, then the mappings and the deletions.
+func splitIdentityBatch(b IdentityBatch, maxItems int) []IdentityBatch {
+	chunks := []IdentityBatch{}
+	current := IdentityBatch{}
+	add := func(i Identity, list *[]Identity) {
+		*list = append(*list, i)
+		if current.len() == maxItems {
+			chunks = append(chunks, current)
+			current = IdentityBatch{}
+		}
+	}
+
+	for _, i := range b.Members {
+		add(i, &current.Members)
+	}
+	for _, i := range b.Mappings {
+		add(i, &current.Mappings)
+	}
+	for _, i := range b.Deleted {
+		add(i, &current.Deleted)
+	}
+
+	if current.len() != 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
@@ -0,0 +1,135 @@
+package pushapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func user(name string) pushapi.Identity {
+	return pushapi.Identity{Identity: pushapi.PermissionIdentity{Name: name, Type: pushapi.IdentityTypeUser}}
+}
+
+func TestPushIdentityBatchSplits(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	batch := pushapi.IdentityBatch{
+		Members: []pushapi.Identity{user("a@example.com"), user("b@example.com"), user("c@example.com")},
+		Deleted: []pushapi.Identity{user("d@example.com"), user("e@example.com")},
+	}
+	progress := []pushapi.BatchProgress{}
+	err := server.client(t).PushIdentityBatch(batch, "myprovider", pushapi.BatchOptions{
+		MaxItems: 2,
+		Progress: func(p pushapi.BatchProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	// Every chunk is uploaded to a file container, then applied
+	if len(server.requests) != 6 {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", 6, server.requests)
+	}
+	for i := 0; i < 6; i += 2 {
+		if server.requests[i] != "POST /myorg/files" {
+			t.Errorf("unexpected request.  expected %v, actual %v", "POST /myorg/files", server.requests[i])
+		}
+		expected := "PUT /myorg/providers/myprovider/permissions/batch?fileId=file1"
+		if server.requests[i+1] != expected {
+			t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[i+1])
+		}
+	}
+
+	expectedProgress := []pushapi.BatchProgress{
+		{Sent: 2, Total: 5, Containers: 1},
+		{Sent: 4, Total: 5, Containers: 2},
+		{Sent: 5, Total: 5, Containers: 3},
+	}
+	if fmt.Sprint(progress) != fmt.Sprint(expectedProgress) {
+		t.Errorf("unexpected progress.  expected %v, actual %v", expectedProgress, progress)
+	}
+
+	// The last upload holds the last chunk
+	last := pushapi.IdentityBatch{}
+	if err := json.Unmarshal(server.uploads["/upload/file1"], &last); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(last.Members) != 0 || len(last.Deleted) != 1 || last.Deleted[0].Identity.Name != "e@example.com" {
+		t.Errorf("unexpected last chunk.  expected the deletion of e@example.com, actual %+v", last)
+	}
+}
+
+func TestPushIdentityBatchValidates(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	batch := pushapi.IdentityBatch{
+		Members: []pushapi.Identity{user("a@example.com"), {Identity: pushapi.PermissionIdentity{Type: pushapi.IdentityTypeUser}}},
+	}
+	err := server.client(t).PushIdentityBatch(batch, "myprovider", pushapi.BatchOptions{})
+	errs, ok := err.(pushapi.ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Field != "members[1].identity.name" {
+		t.Errorf("unexpected error.  expected an error on %v, actual %v", "members[1].identity.name", err)
+	}
+	if len(server.requests) != 0 {
+		t.Errorf("unexpected requests.  expected none, actual %v", server.requests)
+	}
+
+	if err := server.client(t).PushIdentityBatch(pushapi.IdentityBatch{}, "", pushapi.BatchOptions{}); err == nil {
+		t.Errorf("expected an error without providerID")
+	}
+}
+
+func TestPushIdentityOrderingID(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+	client := server.client(t)
+
+	i := user("a@example.com")
+	i.OrderingID = 1234
+	if err := client.PushIdentity(i, "myprovider"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	batch := pushapi.IdentityBatch{Members: []pushapi.Identity{user("b@example.com")}}
+	if err := client.PushIdentityBatch(batch, "myprovider", pushapi.BatchOptions{OrderingID: 5678}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	expected := []string{
+		"PUT /myorg/providers/myprovider/permissions?orderingId=1234",
+		"POST /myorg/files",
+		"PUT /myorg/providers/myprovider/permissions/batch?fileId=file1&orderingId=5678",
+	}
+	if fmt.Sprint(server.requests) != fmt.Sprint(expected) {
+		t.Errorf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+}
+
+func TestDeleteIdentitiesOlderThan(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	if err := server.client(t).DeleteIdentitiesOlderThan("myprovider", 1234); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	expected := "DELETE /myorg/providers/myprovider/permissions/olderthan?orderingId=1234"
+	if len(server.requests) != 1 || server.requests[0] != expected {
+		t.Errorf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+}
+
+func TestRefreshSecurityProvider(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	if err := server.client(t).RefreshSecurityProvider("myprovider"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	expected := "POST /platform/myorg/securityproviders/myprovider/refresh"
+	if len(server.requests) != 1 || server.requests[0] != expected {
+		t.Errorf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+}
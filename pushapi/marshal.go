@@ -0,0 +1,186 @@
+package pushapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Marshal converts a struct, or a pointer to a struct, to a Document.
+//
+// Every exported field becomes a field of the document, named after the
+// lowercased name of the struct field unless a tag gives another name:
+//
+//	// Field appears in the document as "myfield"
+//	Field string `coveo:"myfield"`
+//
+//	// Field is omitted from the document if its value is empty
+//	Field string `coveo:"myfield,omitempty"`
+//
+//	// Field is ignored
+//	Field string `coveo:"-"`
+//
+// The tag names "documentId" and "title", and the untagged fields DocumentID
+// and Title, set the DocumentID and the Title of the document instead, their
+// fields must be strings. Values of type time.Time are formatted with
+// DateFormat, slices and arrays become multi-value fields and the fields of
+// embedded structs are promoted as if they were part of the outer struct.
+// Other nested structs are not supported.
+func Marshal(v interface{}) (Document, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return Document{}, errors.New("cannot marshal a nil pointer")
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return Document{}, fmt.Errorf("cannot marshal a %s, expected a struct", value.Kind())
+	}
+
+	d := Document{Fields: map[string]interface{}{}}
+	if err := marshalStruct(value, &d); err != nil {
+		return Document{}, err
+	}
+	return d, nil
+}
+
+func marshalStruct(value reflect.Value, d *Document) error {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("coveo")
+		if tag == "-" {
+			continue
+		}
+
+		name, options := parseTag(tag)
+		fieldValue := value.Field(i)
+
+		if field.Anonymous && len(name) == 0 {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded.Type() != timeType {
+				if err := marshalStruct(embedded, d); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if len(field.PkgPath) != 0 {
+			// Unexported field
+			continue
+		}
+		if len(name) == 0 {
+			name = strings.ToLower(field.Name)
+			if name == strings.ToLower(keyDocumentID) {
+				name = keyDocumentID
+			}
+		}
+		if options.contains("omitempty") && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		switch name {
+		case keyDocumentID, keyTitle:
+			if fieldValue.Kind() != reflect.String {
+				return fmt.Errorf("the %s field %s must be a string", name, field.Name)
+			}
+			if name == keyDocumentID {
+				d.DocumentID = fieldValue.String()
+			} else {
+				d.Title = fieldValue.String()
+			}
+			continue
+		}
+
+		converted, err := convertValue(fieldValue)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+		d.Fields[name] = converted
+	}
+	return nil
+}
+
+func convertValue(value reflect.Value) (interface{}, error) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return convertValue(value.Elem())
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return value.Interface().(time.Time).Format(DateFormat), nil
+		}
+		return nil, fmt.Errorf("unsupported struct type %s", value.Type())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface(), nil
+		}
+		values := make([]interface{}, value.Len())
+		for i := range values {
+			converted, err := convertValue(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = converted
+		}
+		return values, nil
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128:
+		return nil, fmt.Errorf("unsupported type %s", value.Type())
+	default:
+		return value.Interface(), nil
+	}
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+func (o tagOptions) contains(option string) bool {
+	for _, s := range strings.Split(string(o), ",") {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
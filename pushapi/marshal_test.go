@@ -0,0 +1,167 @@
+package pushapi_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+type audit struct {
+	Created time.Time `coveo:"createddate"`
+	Owner   string    `coveo:",omitempty"`
+}
+
+type article struct {
+	audit
+	URL     string   `coveo:"documentId"`
+	Name    string   `coveo:"title"`
+	Tags    []string `coveo:"tags"`
+	Rating  int      `coveo:"rating,omitempty"`
+	Summary *string
+	Secret  string `coveo:"-"`
+}
+
+func TestMarshal(t *testing.T) {
+	d, err := pushapi.Marshal(&article{
+		audit:  audit{Created: time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+		URL:    "https://example.com/article",
+		Name:   "An article",
+		Tags:   []string{"a", "b"},
+		Secret: "hidden",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if d.DocumentID != "https://example.com/article" || d.Title != "An article" {
+		t.Fatalf("unexpected document.  expected documentId and title from the tags, actual %+v", d)
+	}
+
+	expected := map[string]interface{}{
+		"createddate": "2017-01-02T03:04:05Z",
+		"tags":        []interface{}{"a", "b"},
+		"summary":     nil,
+	}
+	if !reflect.DeepEqual(expected, d.Fields) {
+		t.Fatalf("unexpected fields.  expected %v, actual %v", expected, d.Fields)
+	}
+}
+
+func TestMarshalUntaggedDocumentID(t *testing.T) {
+	d, err := pushapi.Marshal(struct {
+		DocumentID string
+		Title      string
+		Author     string
+	}{"https://example.com/a", "A", "Me"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if d.DocumentID != "https://example.com/a" || d.Title != "A" {
+		t.Errorf("unexpected document.  expected documentId and title from the field names, actual %+v", d)
+	}
+	expected := map[string]interface{}{"author": "Me"}
+	if !reflect.DeepEqual(expected, d.Fields) {
+		t.Errorf("unexpected fields.  expected %v, actual %v", expected, d.Fields)
+	}
+}
+
+func TestMarshalDates(t *testing.T) {
+	date := time.Date(2017, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+	d, err := pushapi.Marshal(struct {
+		Date     time.Time    `coveo:"date"`
+		Pointer  *time.Time   `coveo:"pointer"`
+		Nil      *time.Time   `coveo:"nil"`
+		Zero     time.Time    `coveo:"zero,omitempty"`
+		Releases []time.Time  `coveo:"releases"`
+		Any      interface{}  `coveo:"any"`
+		Unset    *time.Time   `coveo:"unset,omitempty"`
+		Items    [1]time.Time `coveo:"items"`
+	}{
+		Date:     date,
+		Pointer:  &date,
+		Releases: []time.Time{date, date.Add(time.Hour)},
+		Any:      date,
+		Items:    [1]time.Time{date},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	formatted := date.Format(pushapi.DateFormat)
+	expected := map[string]interface{}{
+		"date":     formatted,
+		"pointer":  formatted,
+		"nil":      nil,
+		"releases": []interface{}{formatted, date.Add(time.Hour).Format(pushapi.DateFormat)},
+		"any":      formatted,
+		"items":    []interface{}{formatted},
+	}
+	if !reflect.DeepEqual(expected, d.Fields) {
+		t.Errorf("unexpected fields.  expected %v, actual %v", expected, d.Fields)
+	}
+}
+
+type location struct {
+	City string
+}
+
+func TestMarshalNestedStructs(t *testing.T) {
+	// Embedded structs are promoted, through pointers too
+	d, err := pushapi.Marshal(struct {
+		*location
+		Name string
+	}{&location{City: "Quebec"}, "A"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	expected := map[string]interface{}{"city": "Quebec", "name": "A"}
+	if !reflect.DeepEqual(expected, d.Fields) {
+		t.Errorf("unexpected fields.  expected %v, actual %v", expected, d.Fields)
+	}
+
+	// A nil embedded pointer is skipped
+	d, err = pushapi.Marshal(struct {
+		*location
+		Name string
+	}{nil, "A"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if _, ok := d.Fields["city"]; ok {
+		t.Errorf("unexpected fields.  expected no city, actual %v", d.Fields)
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	var nilArticle *article
+	tests := []struct {
+		name  string
+		value interface{}
+		error string
+	}{
+		{"nested struct", struct{ Location location }{}, "Location"},
+		{"nested struct in a slice", struct{ Locations []location }{[]location{{}}}, "Locations"},
+		{"channel", struct{ Events chan int }{make(chan int)}, "Events"},
+		{"function", struct{ Callback func() }{func() {}}, "Callback"},
+		{"non string documentId", struct {
+			ID int `coveo:"documentId"`
+		}{1}, "ID"},
+		{"non string title", struct{ Title []string }{}, "Title"},
+		{"not a struct", "https://example.com", "string"},
+		{"nil pointer", nilArticle, "nil"},
+	}
+	for _, test := range tests {
+		_, err := pushapi.Marshal(test.value)
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.error) {
+			t.Errorf("%s: unexpected error.  expected it to mention %v, actual %v", test.name, test.error, err)
+		}
+	}
+}
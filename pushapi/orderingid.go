@@ -0,0 +1,24 @@
+package pushapi
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var lastOrderingID int64
+
+// NewOrderingID returns an ordering ID based on the current time in
+// milliseconds. Ordering IDs returned by successive calls are strictly
+// increasing, even when called more than once per millisecond.
+func NewOrderingID() int64 {
+	for {
+		last := atomic.LoadInt64(&lastOrderingID)
+		next := time.Now().UnixNano() / int64(time.Millisecond)
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastOrderingID, last, next) {
+			return next
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package pushapi
+
+import "errors"
+
+// PartialUpdateOperator is the operation applied to a field of a catalog item
+type PartialUpdateOperator string
+
+const (
+	// OperatorArrayAppend adds values to a multi-value field
+	OperatorArrayAppend PartialUpdateOperator = "arrayAppend"
+	// OperatorArrayRemove removes values from a multi-value field
+	OperatorArrayRemove PartialUpdateOperator = "arrayRemove"
+	// OperatorFieldValueReplace replaces the value of a field, a nil value
+	// removes the field
+	OperatorFieldValueReplace PartialUpdateOperator = "fieldValueReplace"
+)
+
+// PartialUpdate updates a field of a catalog item without sending the whole
+// item again
+type PartialUpdate struct {
+	DocumentID string                `json:"documentId"`
+	Operator   PartialUpdateOperator `json:"operator"`
+	Field      string                `json:"field"`
+	Value      interface{}           `json:"value"`
+}
+
+// PartialUpdates builds the partial updates of a catalog item
+type PartialUpdates struct {
+	documentID string
+	operations []PartialUpdate
+}
+
+// NewPartialUpdates starts the partial updates of the item with the
+// documentID
+func NewPartialUpdates(documentID string) *PartialUpdates {
+	return &PartialUpdates{documentID: documentID}
+}
+
+// AddTo adds the values to the multi-value field
+func (p *PartialUpdates) AddTo(field string, values ...interface{}) *PartialUpdates {
+	return p.add(OperatorArrayAppend, field, values)
+}
+
+// RemoveFrom removes the values from the multi-value field
+func (p *PartialUpdates) RemoveFrom(field string, values ...interface{}) *PartialUpdates {
+	return p.add(OperatorArrayRemove, field, values)
+}
+
+// Replace replaces the value of the field
+func (p *PartialUpdates) Replace(field string, value interface{}) *PartialUpdates {
+	return p.add(OperatorFieldValueReplace, field, value)
+}
+
+func (p *PartialUpdates) add(operator PartialUpdateOperator, field string, value interface{}) *PartialUpdates {
+	p.operations = append(p.operations, PartialUpdate{
+		DocumentID: p.documentID,
+		Operator:   operator,
+		Field:      field,
+		Value:      value,
+	})
+	return p
+}
+
+// Operations returns the partial updates built, in order
+func (p *PartialUpdates) Operations() []PartialUpdate {
+	return p.operations
+}
+
+// AddPartialUpdates adds the partial updates to the update
+func (u *CatalogUpdate) AddPartialUpdates(updates ...*PartialUpdates) {
+	for _, p := range updates {
+		u.PartialUpdate = append(u.PartialUpdate, p.Operations()...)
+	}
+}
+
+func (p PartialUpdate) validate() error {
+	if len(p.DocumentID) == 0 {
+		return errors.New("You need to provide a documentID")
+	}
+	if len(p.Field) == 0 {
+		return errors.New("You need to provide a field name")
+	}
+	switch p.Operator {
+	case OperatorArrayAppend, OperatorArrayRemove, OperatorFieldValueReplace:
+		return nil
+	}
+	return &ValidationError{Field: p.Field, Message: "unknown partial update operator " + string(p.Operator)}
+}
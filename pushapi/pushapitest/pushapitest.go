@@ -0,0 +1,41 @@
+// Package pushapitest provides a pushapi.Client recording the operations it
+// receives, for the tests of the packages built on the Push API.
+package pushapitest
+
+import (
+	"sync"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// BatchClient records the batches instead of sending them. The other
+// operations of pushapi.Client are not implemented.
+type BatchClient struct {
+	pushapi.Client
+	mu      sync.Mutex
+	batches []pushapi.Batch
+}
+
+// BatchPush records the batch
+func (c *BatchClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batches = append(c.batches, b)
+	return nil
+}
+
+// Batches returns the batches recorded so far
+func (c *BatchClient) Batches() []pushapi.Batch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]pushapi.Batch(nil), c.batches...)
+}
+
+// Documents returns the documents added or updated by the recorded batches
+func (c *BatchClient) Documents() []pushapi.Document {
+	documents := []pushapi.Document{}
+	for _, b := range c.Batches() {
+		documents = append(documents, b.AddOrUpdate...)
+	}
+	return documents
+}
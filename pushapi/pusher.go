@@ -0,0 +1,287 @@
+package pushapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DefaultMaxBatchSize is the maximum size in bytes of the JSON payload of
+	// a batch sent by a Pusher when none is given. The Push API refuses file
+	// containers larger than 256 MB.
+	DefaultMaxBatchSize = 100 * 1024 * 1024
+	// DefaultPusherConcurrency is the number of batches a Pusher sends at the
+	// same time when none is given
+	DefaultPusherConcurrency = 2
+)
+
+// batchOverhead is the size of the JSON payload of an empty batch, rounded up
+const batchOverhead = 64
+
+// ErrPusherClosed is returned when adding to a Pusher that was closed
+var ErrPusherClosed = errors.New("the pusher is closed")
+
+// PusherConfig is used to configure a new Pusher
+type PusherConfig struct {
+	// SourceID is the source receiving the documents
+	SourceID string
+	// MaxBatchSize is the maximum size in bytes of a batch payload,
+	// DefaultMaxBatchSize if zero
+	MaxBatchSize int
+	// Concurrency is the number of batches sent at the same time,
+	// DefaultPusherConcurrency if zero
+	Concurrency int
+	// OnBatch is called after each batch is sent, from the goroutine that
+	// sent it. It must not call the methods of the Pusher, which may wait for
+	// the worker running it.
+	OnBatch func(BatchResult)
+}
+
+// BatchResult is the outcome of sending a single batch
+type BatchResult struct {
+	// Batch is the batch that was sent
+	Batch Batch
+	// OrderingID is the ordering ID the batch was sent with
+	OrderingID int64
+	Documents  int
+	Deletions  int
+	Size       int
+	Err        error
+}
+
+// PusherStats are the totals of everything sent by a Pusher
+type PusherStats struct {
+	Batches         int
+	FailedBatches   int
+	Documents       int
+	Deletions       int
+	FailedDocuments int
+	FailedDeletions int
+	Bytes           int64
+}
+
+// Pusher splits the documents and deletions it receives in batches smaller
+// than the Push API payload limit and sends them through file containers.
+// Every batch is sent with a new ordering ID, so a later batch wins over an
+// earlier one holding the same document. Its methods are safe for concurrent
+// use.
+type Pusher struct {
+	client Client
+	config PusherConfig
+
+	mu      sync.Mutex
+	closed  bool
+	current Batch
+	size    int
+
+	batches chan pendingBatch
+	sending sync.WaitGroup
+	workers sync.WaitGroup
+	done    chan struct{}
+
+	statsMu  sync.Mutex
+	stats    PusherStats
+	firstErr error
+}
+
+type pendingBatch struct {
+	batch Batch
+	size  int
+}
+
+// NewPusher returns a Pusher sending its batches with the client
+func NewPusher(c Client, config PusherConfig) *Pusher {
+	if config.MaxBatchSize <= 0 {
+		config.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultPusherConcurrency
+	}
+
+	p := &Pusher{
+		client:  c,
+		config:  config,
+		size:    batchOverhead,
+		batches: make(chan pendingBatch, config.Concurrency),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < config.Concurrency; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Add queues a document to add or update. It blocks while all the workers
+// are busy sending their batch.
+func (p *Pusher) Add(d Document) error {
+	if len(d.DocumentID) == 0 {
+		return errors.New("You need to provide a documentID")
+	}
+
+	size, err := itemSize(d)
+	if err != nil {
+		return err
+	}
+
+	return p.add(size, func(b *Batch) {
+		b.AddOrUpdate = append(b.AddOrUpdate, d)
+	})
+}
+
+// Delete queues a document to delete. It blocks while all the workers are
+// busy sending their batch.
+func (p *Pusher) Delete(d DeletedDocument) error {
+	if len(d.DocumentID) == 0 {
+		return errors.New("You need a documentID")
+	}
+
+	size, err := itemSize(d)
+	if err != nil {
+		return err
+	}
+
+	return p.add(size, func(b *Batch) {
+		b.Delete = append(b.Delete, d)
+	})
+}
+
+// Consume adds every document received on the channel until it is closed
+func (p *Pusher) Consume(documents <-chan Document) error {
+	for d := range documents {
+		if err := p.Add(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close sends the last batch, waits for every batch to be sent and returns the
+// totals. The error is not nil if any batch failed. Closing again waits for
+// the first Close and returns the same totals.
+func (p *Pusher) Close() (PusherStats, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.done
+		return p.result()
+	}
+	p.closed = true
+	last := p.takeLocked()
+	p.mu.Unlock()
+
+	p.send(last)
+	p.sending.Wait()
+	close(p.batches)
+	p.workers.Wait()
+	close(p.done)
+	return p.result()
+}
+
+func (p *Pusher) add(size int, appendTo func(*Batch)) error {
+	if size+batchOverhead > p.config.MaxBatchSize {
+		return fmt.Errorf("the item is %d bytes, larger than the maximum batch size of %d bytes", size, p.config.MaxBatchSize)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPusherClosed
+	}
+
+	var full *pendingBatch
+	if p.size+size > p.config.MaxBatchSize {
+		full = p.takeLocked()
+	}
+	appendTo(&p.current)
+	p.size += size
+	p.mu.Unlock()
+
+	// The batch waits for a worker without the lock, so that other goroutines
+	// keep filling the next batch meanwhile
+	p.send(full)
+	return nil
+}
+
+// takeLocked swaps out the current batch, nil if it is empty. The batch must
+// then be given to send.
+func (p *Pusher) takeLocked() *pendingBatch {
+	if len(p.current.AddOrUpdate) == 0 && len(p.current.Delete) == 0 {
+		return nil
+	}
+	full := &pendingBatch{batch: p.current, size: p.size}
+	full.batch.OrderingID = NewOrderingID()
+	p.current = Batch{}
+	p.size = batchOverhead
+	p.sending.Add(1)
+	return full
+}
+
+func (p *Pusher) send(b *pendingBatch) {
+	if b == nil {
+		return
+	}
+	p.batches <- *b
+	p.sending.Done()
+}
+
+func (p *Pusher) work() {
+	defer p.workers.Done()
+	for pending := range p.batches {
+		err := p.client.BatchPush(pending.batch, p.config.SourceID)
+		result := BatchResult{
+			Batch:      pending.batch,
+			OrderingID: pending.batch.OrderingID,
+			Documents:  len(pending.batch.AddOrUpdate),
+			Deletions:  len(pending.batch.Delete),
+			Size:       pending.size,
+			Err:        err,
+		}
+		p.record(result)
+		if p.config.OnBatch != nil {
+			p.config.OnBatch(result)
+		}
+	}
+}
+
+func (p *Pusher) record(r BatchResult) {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	p.stats.Batches++
+	p.stats.Bytes += int64(r.Size)
+	if r.Err != nil {
+		p.stats.FailedBatches++
+		p.stats.FailedDocuments += r.Documents
+		p.stats.FailedDeletions += r.Deletions
+		if p.firstErr == nil {
+			p.firstErr = r.Err
+		}
+		return
+	}
+	p.stats.Documents += r.Documents
+	p.stats.Deletions += r.Deletions
+}
+
+func (p *Pusher) result() (PusherStats, error) {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	if p.firstErr != nil {
+		return p.stats, fmt.Errorf("%d of %d batches failed, first error: %v",
+			p.stats.FailedBatches, p.stats.Batches, p.firstErr)
+	}
+	return p.stats, nil
+}
+
+// itemSize returns the size of the item in the JSON payload of a batch,
+// including its separator
+func itemSize(v interface{}) (int, error) {
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return len(marshalled) + 1, nil
+}
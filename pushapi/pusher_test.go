@@ -0,0 +1,147 @@
+package pushapi_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestPusherSplitsBatches(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	pusher := pushapi.NewPusher(server.client(t), pushapi.PusherConfig{
+		SourceID:     "mysource",
+		MaxBatchSize: 200,
+	})
+	for i := 0; i < 10; i++ {
+		if err := pusher.Add(pushapi.Document{DocumentID: fmt.Sprintf("file://doc%d", i), Data: "some content"}); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	if err := pusher.Delete(pushapi.DeletedDocument{DocumentID: "file://old"}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	stats, err := pusher.Close()
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats.Documents != 10 || stats.Deletions != 1 {
+		t.Fatalf("unexpected totals.  expected %v documents and %v deletions, actual %+v", 10, 1, stats)
+	}
+	if stats.Batches < 2 {
+		t.Fatalf("expected the documents to be split in several batches, actual %+v", stats)
+	}
+	if len(server.requests) != 2*stats.Batches {
+		t.Fatalf("unexpected number of requests.  expected %v, actual %v", 2*stats.Batches, len(server.requests))
+	}
+}
+
+// failingClient fails the batches holding file://bad
+type failingClient struct {
+	pushapi.Client
+}
+
+func (c *failingClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	for _, d := range b.AddOrUpdate {
+		if d.DocumentID == "file://bad" {
+			return errors.New("the batch was refused")
+		}
+	}
+	return nil
+}
+
+func TestPusherReportsFailedBatches(t *testing.T) {
+	var mu sync.Mutex
+	results := []pushapi.BatchResult{}
+	pusher := pushapi.NewPusher(&failingClient{}, pushapi.PusherConfig{
+		SourceID:     "mysource",
+		MaxBatchSize: 200,
+		OnBatch: func(r pushapi.BatchResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, r)
+		},
+	})
+	for _, id := range []string{"file://a", "file://b", "file://bad", "file://c", "file://d"} {
+		if err := pusher.Add(pushapi.Document{DocumentID: id, Data: "some content"}); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+
+	stats, err := pusher.Close()
+	if err == nil || !strings.Contains(err.Error(), "the batch was refused") {
+		t.Errorf("unexpected error.  expected the error of the failed batch, actual %v", err)
+	}
+	if stats.FailedBatches != 1 || stats.Batches < 2 {
+		t.Fatalf("unexpected totals.  expected 1 failed batch out of several, actual %+v", stats)
+	}
+
+	failed := 0
+	orderingIDs := map[int64]bool{}
+	for _, r := range results {
+		if r.OrderingID == 0 || r.OrderingID != r.Batch.OrderingID || orderingIDs[r.OrderingID] {
+			t.Errorf("unexpected ordering ID.  expected a new ordering ID for each batch, actual %v", r.OrderingID)
+		}
+		orderingIDs[r.OrderingID] = true
+		if r.Err == nil {
+			continue
+		}
+		failed++
+		if stats.FailedDocuments != r.Documents || stats.Documents+r.Documents != 5 {
+			t.Errorf("unexpected totals.  expected the %v documents of the failed batch to fail, actual %+v", r.Documents, stats)
+		}
+	}
+	if failed != 1 || len(results) != stats.Batches {
+		t.Errorf("unexpected results.  expected 1 failed batch out of %v, actual %+v", stats.Batches, results)
+	}
+}
+
+// blockingClient holds every batch until release is closed
+type blockingClient struct {
+	pushapi.Client
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	c.started <- struct{}{}
+	<-c.release
+	return nil
+}
+
+func TestPusherCloseTwiceWaits(t *testing.T) {
+	client := &blockingClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	pusher := pushapi.NewPusher(client, pushapi.PusherConfig{SourceID: "mysource"})
+	if err := pusher.Add(pushapi.Document{DocumentID: "file://a"}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	results := make(chan pushapi.PusherStats, 2)
+	closePusher := func() {
+		stats, _ := pusher.Close()
+		results <- stats
+	}
+	go closePusher()
+	<-client.started
+	go closePusher()
+
+	select {
+	case stats := <-results:
+		t.Fatalf("expected Close to wait for the batch being sent, actual %+v", stats)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(client.release)
+	for i := 0; i < 2; i++ {
+		if stats := <-results; stats.Documents != 1 {
+			t.Errorf("unexpected totals.  expected %v document, actual %+v", 1, stats)
+		}
+	}
+}
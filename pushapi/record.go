@@ -0,0 +1,326 @@
+package pushapi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DocumentRecord keeps the IDs of the documents pushed to each source, so
+// that they can be found again without querying the index. Implementations
+// must be safe for concurrent use.
+type DocumentRecord interface {
+	// Add records the document, parentID is the ID of its parent if any
+	Add(sourceID, documentID, parentID string) error
+	Remove(sourceID, documentID string) error
+	// DocumentIDs returns the recorded document IDs of the source, sorted
+	DocumentIDs(sourceID string) ([]string, error)
+	// Children returns the recorded IDs of the documents having documentID
+	// as parent, sorted
+	Children(sourceID, documentID string) ([]string, error)
+}
+
+// NewMemoryRecord returns a DocumentRecord kept in memory
+func NewMemoryRecord() DocumentRecord {
+	return &memoryRecord{sources: map[string]map[string]string{}}
+}
+
+// memoryRecord keeps the parent ID of every document ID of each source
+type memoryRecord struct {
+	mu      sync.RWMutex
+	sources map[string]map[string]string
+}
+
+func (r *memoryRecord) Add(sourceID, documentID, parentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add(sourceID, documentID, parentID)
+	return nil
+}
+
+func (r *memoryRecord) add(sourceID, documentID, parentID string) {
+	ids, ok := r.sources[sourceID]
+	if !ok {
+		ids = map[string]string{}
+		r.sources[sourceID] = ids
+	}
+	ids[documentID] = parentID
+}
+
+func (r *memoryRecord) Remove(sourceID, documentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sources[sourceID], documentID)
+	return nil
+}
+
+func (r *memoryRecord) DocumentIDs(sourceID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.sources[sourceID]))
+	for id := range r.sources[sourceID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (r *memoryRecord) Children(sourceID, documentID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := []string{}
+	for id, parentID := range r.sources[sourceID] {
+		if parentID == documentID && len(parentID) != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// NewFileRecord returns a DocumentRecord persisted to an append-only file,
+// which is read back when the record is opened. A last line left incomplete
+// by a crash is ignored and cut from the file. Call Close once done.
+func NewFileRecord(path string) (*FileRecord, error) {
+	r := &FileRecord{memoryRecord: memoryRecord{sources: map[string]map[string]string{}}}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	size, err := r.load(f)
+	if err == nil {
+		err = f.Truncate(size)
+	}
+	if err == nil {
+		_, err = f.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid document record %s: %v", path, err)
+	}
+	r.file = f
+	return r, nil
+}
+
+// FileRecord is a DocumentRecord persisted to a file, see NewFileRecord. Each
+// line is an operation: "+", the source ID and the document ID to add a
+// document, ">", the source ID, the parent ID and the document ID to add a
+// child document, "-", the source ID and the document ID to remove it.
+type FileRecord struct {
+	memoryRecord
+	file *os.File
+}
+
+// Add records the document ID
+func (r *FileRecord) Add(sourceID, documentID, parentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	if len(parentID) == 0 {
+		err = r.append("+", sourceID, documentID)
+	} else {
+		err = r.append(">", sourceID, parentID, documentID)
+	}
+	if err != nil {
+		return err
+	}
+	r.add(sourceID, documentID, parentID)
+	return nil
+}
+
+// Remove forgets the document ID
+func (r *FileRecord) Remove(sourceID, documentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.append("-", sourceID, documentID); err != nil {
+		return err
+	}
+	delete(r.sources[sourceID], documentID)
+	return nil
+}
+
+// Close closes the underlying file
+func (r *FileRecord) Close() error {
+	return r.file.Close()
+}
+
+// append writes an operation line. The document ID is always the last field,
+// it is the only one that can contain tabs.
+func (r *FileRecord) append(fields ...string) error {
+	for i, field := range fields {
+		if strings.Contains(field, "\n") || (i < len(fields)-1 && strings.Contains(field, "\t")) {
+			return errors.New("the source and parent IDs cannot contain tabs, the IDs cannot contain new lines")
+		}
+	}
+	_, err := fmt.Fprintf(r.file, "%s\n", strings.Join(fields, "\t"))
+	return err
+}
+
+// load reads the operations of the file, and returns the size of its complete
+// lines
+func (r *FileRecord) load(f *os.File) (int64, error) {
+	reader := bufio.NewReader(f)
+	size := int64(0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// Anything after the last new line is an operation whose write was
+			// interrupted, it is ignored
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += int64(len(line))
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "+\t"):
+			if parts := strings.SplitN(line, "\t", 3); len(parts) == 3 {
+				r.add(parts[1], parts[2], "")
+			}
+		case strings.HasPrefix(line, ">\t"):
+			if parts := strings.SplitN(line, "\t", 4); len(parts) == 4 {
+				r.add(parts[1], parts[3], parts[2])
+			}
+		case strings.HasPrefix(line, "-\t"):
+			if parts := strings.SplitN(line, "\t", 3); len(parts) == 3 {
+				delete(r.sources[parts[1]], parts[2])
+			}
+		}
+	}
+}
+
+// removeWithChildren forgets the document and its children, recursively
+func removeWithChildren(r DocumentRecord, sourceID, documentID string) error {
+	children, err := r.Children(sourceID, documentID)
+	if err != nil {
+		return err
+	}
+	if err := r.Remove(sourceID, documentID); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := removeWithChildren(r, sourceID, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget forgets the deleted document, and its children if they are deleted
+// too
+func forget(r DocumentRecord, sourceID, documentID string, deleteChildren bool) error {
+	if deleteChildren {
+		return removeWithChildren(r, sourceID, documentID)
+	}
+	return r.Remove(sourceID, documentID)
+}
+
+// RecordingClient is a Client recording the documents pushed and deleted
+// through it in a DocumentRecord
+type RecordingClient struct {
+	Client
+	Record DocumentRecord
+}
+
+// NewRecordingClient returns a Client recording the documents pushed through c
+func NewRecordingClient(c Client, r DocumentRecord) *RecordingClient {
+	return &RecordingClient{Client: c, Record: r}
+}
+
+// PushDocument pushes the document and records its ID
+func (c *RecordingClient) PushDocument(d Document, sourceID string) (string, error) {
+	resp, err := c.Client.PushDocument(d, sourceID)
+	if err != nil {
+		return resp, err
+	}
+	return resp, c.Record.Add(sourceID, d.DocumentID, d.ParentID)
+}
+
+// DeleteDocument deletes the document and forgets its ID
+func (c *RecordingClient) DeleteDocument(documentID, sourceID string) error {
+	return c.DeleteDocumentWithOptions(documentID, sourceID, DeleteOptions{})
+}
+
+// DeleteDocumentWithOptions deletes the document and forgets its ID, and the
+// IDs of its children with DeleteChildren
+func (c *RecordingClient) DeleteDocumentWithOptions(documentID, sourceID string, o DeleteOptions) error {
+	if err := c.Client.DeleteDocumentWithOptions(documentID, sourceID, o); err != nil {
+		return err
+	}
+	return forget(c.Record, sourceID, documentID, o.DeleteChildren)
+}
+
+// BatchPush sends the batch and records the documents added and deleted
+func (c *RecordingClient) BatchPush(b Batch, sourceID string) error {
+	if err := c.Client.BatchPush(b, sourceID); err != nil {
+		return err
+	}
+	for _, d := range b.AddOrUpdate {
+		if err := c.Record.Add(sourceID, d.DocumentID, d.ParentID); err != nil {
+			return err
+		}
+	}
+	for _, d := range b.Delete {
+		if err := forget(c.Record, sourceID, d.DocumentID, d.DeleteChildren); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteByURIPrefix deletes every recorded document of the source whose ID
+// starts with prefix, in batches, and forgets them. It returns the number of
+// documents deleted.
+func DeleteByURIPrefix(c Client, r DocumentRecord, sourceID, prefix string, o DeleteOptions) (int, error) {
+	if len(prefix) == 0 {
+		return 0, errors.New("You need a prefix")
+	}
+
+	ids, err := r.DocumentIDs(sourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	batch := Batch{OrderingID: o.OrderingID}
+	flush := func() error {
+		if len(batch.Delete) == 0 {
+			return nil
+		}
+		if err := c.BatchPush(batch, sourceID); err != nil {
+			return err
+		}
+		for _, d := range batch.Delete {
+			if err := forget(r, sourceID, d.DocumentID, d.DeleteChildren); err != nil {
+				return err
+			}
+		}
+		deleted += len(batch.Delete)
+		batch.Delete = nil
+		return nil
+	}
+
+	for _, id := range ids {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		batch.Delete = append(batch.Delete, DeletedDocument{DocumentID: id, DeleteChildren: o.DeleteChildren})
+		if len(batch.Delete) == DefaultMaxBatchItems {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, flush()
+}
@@ -0,0 +1,188 @@
+package pushapi_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func tempRecordPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return filepath.Join(dir, "record"), func() { os.RemoveAll(dir) }
+}
+
+func assertDocumentIDs(t *testing.T, r pushapi.DocumentRecord, sourceID string, expected []string) {
+	ids, err := r.DocumentIDs(sourceID)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("unexpected document IDs.  expected %v, actual %v", expected, ids)
+	}
+}
+
+func TestFileRecordReplay(t *testing.T) {
+	path, cleanup := tempRecordPath(t)
+	defer cleanup()
+
+	r, err := pushapi.NewFileRecord(path)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	r.Add("mysource", "file://a", "")
+	r.Add("mysource", "file://b", "")
+	r.Add("mysource", "file://a#1", "file://a")
+	r.Add("othersource", "file://c", "")
+	r.Remove("mysource", "file://b")
+	r.Close()
+
+	r, err = pushapi.NewFileRecord(path)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer r.Close()
+
+	assertDocumentIDs(t, r, "mysource", []string{"file://a", "file://a#1"})
+	assertDocumentIDs(t, r, "othersource", []string{"file://c"})
+	if children, _ := r.Children("mysource", "file://a"); !reflect.DeepEqual(children, []string{"file://a#1"}) {
+		t.Errorf("unexpected children.  expected %v, actual %v", []string{"file://a#1"}, children)
+	}
+}
+
+func TestFileRecordTruncatedLine(t *testing.T) {
+	path, cleanup := tempRecordPath(t)
+	defer cleanup()
+
+	// The write of the last operation was interrupted
+	if err := ioutil.WriteFile(path, []byte("+\tmysource\tfile://a\n+\tmysource\tfile://"), 0644); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	r, err := pushapi.NewFileRecord(path)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	assertDocumentIDs(t, r, "mysource", []string{"file://a"})
+
+	// The next operation is not appended to the incomplete line
+	if err := r.Add("mysource", "file://b", ""); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	r.Close()
+
+	r, err = pushapi.NewFileRecord(path)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer r.Close()
+	assertDocumentIDs(t, r, "mysource", []string{"file://a", "file://b"})
+}
+
+func TestFileRecordRejectsInvalidIDs(t *testing.T) {
+	path, cleanup := tempRecordPath(t)
+	defer cleanup()
+
+	r, err := pushapi.NewFileRecord(path)
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer r.Close()
+
+	if err := r.Add("my\tsource", "file://a", ""); err == nil {
+		t.Errorf("expected an error for a source ID with a tab")
+	}
+	if err := r.Add("mysource", "file://a\n", ""); err == nil {
+		t.Errorf("expected an error for a document ID with a new line")
+	}
+	if err := r.Add("mysource", "file://a\tb", ""); err != nil {
+		t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+}
+
+// deleteClient records the batches, and accepts every other operation
+type deleteClient struct {
+	pushapi.Client
+	batches []pushapi.Batch
+}
+
+func (c *deleteClient) PushDocument(d pushapi.Document, sourceID string) (string, error) {
+	return "", nil
+}
+
+func (c *deleteClient) DeleteDocumentWithOptions(documentID, sourceID string, o pushapi.DeleteOptions) error {
+	return nil
+}
+
+func (c *deleteClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	c.batches = append(c.batches, b)
+	return nil
+}
+
+func TestRecordingClientDeleteChildren(t *testing.T) {
+	record := pushapi.NewMemoryRecord()
+	c := pushapi.NewRecordingClient(&deleteClient{}, record)
+
+	documents := []pushapi.Document{
+		{DocumentID: "file://a"},
+		{DocumentID: "file://a#1", ParentID: "file://a"},
+		{DocumentID: "file://a#1#1", ParentID: "file://a#1"},
+		{DocumentID: "file://b"},
+	}
+	if err := c.BatchPush(pushapi.Batch{AddOrUpdate: documents}, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	assertDocumentIDs(t, record, "mysource", []string{"file://a", "file://a#1", "file://a#1#1", "file://b"})
+
+	// Without DeleteChildren, the children are kept
+	if err := c.DeleteDocument("file://a#1", "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	assertDocumentIDs(t, record, "mysource", []string{"file://a", "file://a#1#1", "file://b"})
+
+	c.PushDocument(pushapi.Document{DocumentID: "file://a#1", ParentID: "file://a"}, "mysource")
+	if err := c.DeleteDocumentWithOptions("file://a", "mysource", pushapi.DeleteOptions{DeleteChildren: true}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	assertDocumentIDs(t, record, "mysource", []string{"file://b"})
+}
+
+func TestDeleteByURIPrefix(t *testing.T) {
+	record := pushapi.NewMemoryRecord()
+	for _, id := range []string{"file://docs/a", "file://docs/b", "file://other/c"} {
+		record.Add("mysource", id, "")
+	}
+	record.Add("mysource", "file://other/c#1", "file://docs/a")
+
+	client := &deleteClient{}
+	deleted, err := pushapi.DeleteByURIPrefix(client, record, "mysource", "file://docs/", pushapi.DeleteOptions{
+		OrderingID:     42,
+		DeleteChildren: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if deleted != 2 {
+		t.Errorf("unexpected deleted count.  expected %v, actual %v", 2, deleted)
+	}
+	if len(client.batches) != 1 || len(client.batches[0].Delete) != 2 || client.batches[0].OrderingID != 42 {
+		t.Fatalf("unexpected batches.  expected 1 batch of 2 deletions, actual %+v", client.batches)
+	}
+	for _, d := range client.batches[0].Delete {
+		if !d.DeleteChildren {
+			t.Errorf("expected %v to be deleted with its children", d.DocumentID)
+		}
+	}
+	assertDocumentIDs(t, record, "mysource", []string{"file://other/c"})
+
+	if _, err := pushapi.DeleteByURIPrefix(client, record, "mysource", "", pushapi.DeleteOptions{}); err == nil {
+		t.Errorf("expected an error without prefix")
+	}
+}
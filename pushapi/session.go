@@ -0,0 +1,145 @@
+package pushapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SourceStatus is the activity status of a push source
+type SourceStatus string
+
+const (
+	// SourceStatusRebuild means the content of the source is being rebuilt
+	SourceStatusRebuild SourceStatus = "REBUILD"
+	// SourceStatusRefresh means the content of the source is being refreshed
+	SourceStatusRefresh SourceStatus = "REFRESH"
+	// SourceStatusIncremental means the source receives incremental changes
+	SourceStatusIncremental SourceStatus = "INCREMENTAL"
+	// SourceStatusIdle means the source is not receiving content
+	SourceStatusIdle SourceStatus = "IDLE"
+)
+
+// Validate returns an error if the status is not one of the known statuses
+func (s SourceStatus) Validate() error {
+	switch s {
+	case SourceStatusRebuild, SourceStatusRefresh, SourceStatusIncremental, SourceStatusIdle:
+		return nil
+	}
+	return fmt.Errorf("invalid source status %q", s)
+}
+
+// SetSourceStatus changes the activity status of the source
+func (c *client) SetSourceStatus(sourceID string, status SourceStatus) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	if err := status.Validate(); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s%s/sources/%s/status?statusType=%s",
+		c.endpoint, c.organizationid, url.PathEscape(sourceID), status)
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.sendRequest(req)
+	return err
+}
+
+// Session sets the status of a source while content is pushed to it, and puts
+// it back to IDLE once done. With DeleteOlderThan, a session is a full
+// refresh: the documents that were not pushed during the session are deleted.
+type Session struct {
+	// Client is the client used to change the status of the source
+	Client Client
+	// SourceID is the source receiving the content
+	SourceID string
+	// Status is the status of the source while pushing, SourceStatusRebuild
+	// if empty
+	Status SourceStatus
+	// DeleteOlderThan deletes, after a successful push, the documents of the
+	// source last updated with an ordering ID lower than StartOrderingID
+	DeleteOlderThan bool
+	// StartOrderingID is set by Run when the session starts. The documents
+	// pushed during the session must have a higher ordering ID, which is the
+	// case of the ones pushed with the client given to push.
+	StartOrderingID int64
+}
+
+// Run sets the status of the source, calls push, deletes the older documents
+// if asked to, then sets the status back to IDLE even if push fails or panics.
+// A panic is propagated once the status is reset. The error of push takes
+// precedence over the error of the reset.
+//
+// The documents must be pushed with the client given to push. It sends every
+// document operation without ordering ID with one from NewOrderingID, so that
+// the ordering IDs of the session and StartOrderingID come from the same
+// clock. Left to the Push API, they would come from the server clock and
+// DeleteOlderThan could delete the documents just pushed.
+func (s *Session) Run(push func(c Client) error) (err error) {
+	status := s.Status
+	if len(status) == 0 {
+		status = SourceStatusRebuild
+	}
+
+	if err := s.Client.SetSourceStatus(s.SourceID, status); err != nil {
+		return err
+	}
+	s.StartOrderingID = NewOrderingID()
+
+	defer func() {
+		recovered := recover()
+		idleErr := s.Client.SetSourceStatus(s.SourceID, SourceStatusIdle)
+		if recovered != nil {
+			panic(recovered)
+		}
+		if err == nil && idleErr != nil {
+			err = idleErr
+		}
+	}()
+
+	if err := push(sessionClient{s.Client}); err != nil {
+		return err
+	}
+
+	if s.DeleteOlderThan {
+		return s.Client.DeleteOlderThan(s.SourceID, s.StartOrderingID)
+	}
+	return nil
+}
+
+// sessionClient gives an ordering ID from NewOrderingID to the document
+// operations sent without one. NewOrderingID never returns the same value
+// twice, they are all higher than the StartOrderingID of the session.
+type sessionClient struct {
+	Client
+}
+
+func (c sessionClient) PushDocument(d Document, sourceID string) (string, error) {
+	if d.OrderingID == 0 {
+		d.OrderingID = NewOrderingID()
+	}
+	return c.Client.PushDocument(d, sourceID)
+}
+
+func (c sessionClient) DeleteDocument(documentID, sourceID string) error {
+	return c.DeleteDocumentWithOptions(documentID, sourceID, DeleteOptions{})
+}
+
+func (c sessionClient) DeleteDocumentWithOptions(documentID, sourceID string, o DeleteOptions) error {
+	if o.OrderingID == 0 {
+		o.OrderingID = NewOrderingID()
+	}
+	return c.Client.DeleteDocumentWithOptions(documentID, sourceID, o)
+}
+
+func (c sessionClient) BatchPush(b Batch, sourceID string) error {
+	if b.OrderingID == 0 {
+		b.OrderingID = NewOrderingID()
+	}
+	return c.Client.BatchPush(b, sourceID)
+}
@@ -0,0 +1,223 @@
+package pushapi_test
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// orderingID returns the orderingId parameter of a recorded request
+func orderingID(t *testing.T, request string) int64 {
+	u, err := url.Parse(request[strings.Index(request, " ")+1:])
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	id, err := strconv.ParseInt(u.Query().Get("orderingId"), 10, 64)
+	if err != nil {
+		t.Fatalf("unexpected ordering ID in %s: %v", request, err)
+	}
+	return id
+}
+
+func TestSessionRun(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	session := &pushapi.Session{
+		Client:          server.client(t),
+		SourceID:        "mysource",
+		DeleteOlderThan: true,
+	}
+	err := session.Run(func(c pushapi.Client) error {
+		if _, err := c.PushDocument(pushapi.Document{DocumentID: "file://a"}, "mysource"); err != nil {
+			return err
+		}
+		return c.DeleteDocument("file://b", "mysource")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if len(server.requests) != 5 {
+		t.Fatalf("unexpected requests.  expected %v, actual %v", 5, server.requests)
+	}
+	expected := "POST /myorg/sources/mysource/status?statusType=REBUILD"
+	if server.requests[0] != expected {
+		t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[0])
+	}
+	// The documents are pushed with ordering IDs from the same clock as the
+	// start of the session, the older documents are deleted from there
+	for _, request := range server.requests[1:3] {
+		if id := orderingID(t, request); id <= session.StartOrderingID {
+			t.Errorf("unexpected ordering ID.  expected more than %v, actual %v", session.StartOrderingID, id)
+		}
+	}
+	if id := orderingID(t, server.requests[3]); id != session.StartOrderingID {
+		t.Errorf("unexpected deletion ordering ID.  expected %v, actual %v", session.StartOrderingID, id)
+	}
+	expected = "POST /myorg/sources/mysource/status?statusType=IDLE"
+	if server.requests[4] != expected {
+		t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[4])
+	}
+}
+
+func TestSessionKeepsOrderingIDs(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	session := &pushapi.Session{Client: server.client(t), SourceID: "mysource"}
+	err := session.Run(func(c pushapi.Client) error {
+		_, err := c.PushDocument(pushapi.Document{DocumentID: "file://a", OrderingID: 42}, "mysource")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if id := orderingID(t, server.requests[1]); id != 42 {
+		t.Errorf("unexpected ordering ID.  expected %v, actual %v", 42, id)
+	}
+}
+
+func TestDeleteOlderThan(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	if err := server.client(t).DeleteOlderThan("mysource", 1234); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	expected := "DELETE /myorg/sources/mysource/documents/olderthan?orderingId=1234"
+	if len(server.requests) != 1 || server.requests[0] != expected {
+		t.Errorf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+	if err := server.client(t).DeleteOlderThan("", 1234); err == nil {
+		t.Errorf("expected an error without sourceID")
+	}
+}
+
+func TestDeleteDocumentWithOptions(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	err := server.client(t).DeleteDocumentWithOptions("file://a", "mysource", pushapi.DeleteOptions{
+		OrderingID:     1234,
+		DeleteChildren: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	u, err := url.Parse(server.requests[0][len("DELETE "):])
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	query := u.Query()
+	if u.Path != "/myorg/sources/mysource/documents" || query.Get("documentId") != "file://a" ||
+		query.Get("orderingId") != "1234" || query.Get("deleteChildren") != "true" {
+		t.Errorf("unexpected request.  expected the document, ordering ID and deleteChildren, actual %v", server.requests[0])
+	}
+}
+
+func TestSetSourceStatus(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+	c := server.client(t)
+
+	statuses := []pushapi.SourceStatus{
+		pushapi.SourceStatusRebuild,
+		pushapi.SourceStatusRefresh,
+		pushapi.SourceStatusIncremental,
+		pushapi.SourceStatusIdle,
+	}
+	for i, status := range statuses {
+		if err := c.SetSourceStatus("mysource", status); err != nil {
+			t.Fatalf("unexpected error for %v.  expected %v, actual %v", status, nil, err)
+		}
+		expected := "POST /myorg/sources/mysource/status?statusType=" + string(status)
+		if server.requests[i] != expected {
+			t.Errorf("unexpected request.  expected %v, actual %v", expected, server.requests[i])
+		}
+	}
+
+	if err := c.SetSourceStatus("mysource", "SLEEPING"); err == nil {
+		t.Errorf("expected an error for an invalid status")
+	}
+	if err := c.SetSourceStatus("", pushapi.SourceStatusIdle); err == nil {
+		t.Errorf("expected an error without sourceID")
+	}
+	if len(server.requests) != len(statuses) {
+		t.Errorf("unexpected requests.  expected %v, actual %v", len(statuses), server.requests)
+	}
+}
+
+func TestSessionRunResetsStatusOnError(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	session := &pushapi.Session{
+		Client:          server.client(t),
+		SourceID:        "mysource",
+		Status:          pushapi.SourceStatusRefresh,
+		DeleteOlderThan: true,
+	}
+	pushErr := errors.New("the crawl failed")
+	if err := session.Run(func(c pushapi.Client) error { return pushErr }); err != pushErr {
+		t.Errorf("unexpected error.  expected %v, actual %v", pushErr, err)
+	}
+
+	// Nothing is deleted when the push fails
+	expected := []string{
+		"POST /myorg/sources/mysource/status?statusType=REFRESH",
+		"POST /myorg/sources/mysource/status?statusType=IDLE",
+	}
+	if !reflect.DeepEqual(server.requests, expected) {
+		t.Errorf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+	}
+}
+
+func TestSessionRunResetsStatusOnPanic(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	session := &pushapi.Session{Client: server.client(t), SourceID: "mysource"}
+	defer func() {
+		if recovered := recover(); recovered != "crawler panic" {
+			t.Errorf("unexpected panic.  expected %v, actual %v", "crawler panic", recovered)
+		}
+		expected := []string{
+			"POST /myorg/sources/mysource/status?statusType=REBUILD",
+			"POST /myorg/sources/mysource/status?statusType=IDLE",
+		}
+		if !reflect.DeepEqual(server.requests, expected) {
+			t.Errorf("unexpected requests.  expected %v, actual %v", expected, server.requests)
+		}
+	}()
+
+	session.Run(func(c pushapi.Client) error { panic("crawler panic") })
+	t.Errorf("expected the panic to be propagated")
+}
+
+func TestSessionRunStatusError(t *testing.T) {
+	session := &pushapi.Session{Client: &statusClient{}, SourceID: "mysource"}
+	called := false
+	err := session.Run(func(c pushapi.Client) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("expected push not to be called when the status cannot be set, actual error %v", err)
+	}
+}
+
+// statusClient fails to set the status of the source
+type statusClient struct {
+	pushapi.Client
+}
+
+func (c *statusClient) SetSourceStatus(sourceID string, status pushapi.SourceStatus) error {
+	return &pushapi.APIError{StatusCode: 503}
+}
@@ -0,0 +1,212 @@
+package pushapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+)
+
+const (
+	// MaxDocumentSize is the maximum size of the JSON of a document
+	MaxDocumentSize = 256 * 1024 * 1024
+	// MaxPushDocumentSize is the maximum size of the JSON of a document sent
+	// with PushDocument, larger documents must be sent with BatchPush
+	MaxPushDocumentSize = 5 * 1024 * 1024
+	// MaxFieldNameLength is the maximum length of a field name
+	MaxFieldNameLength = 255
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// typedKeys are the reserved keys that can only be set through the typed
+// fields of Document, lowercase reserved keys are legal field names but
+// conflict with the typed field when both are set.
+var typedKeys = map[string]string{
+	keyDocumentID:                "DocumentID",
+	keyClickableURI:              "ClickableURI",
+	keyModifiedDate:              "ModifiedDate",
+	keyFileExtension:             "FileExtension",
+	keyCompressedBinaryData:      "CompressedBinaryData",
+	keyCompressionType:           "CompressionType",
+	keyParentID:                  "ParentID",
+	keyPermissions:               "Permissions",
+	"compressedBinaryDataFileId": "",
+	"orderingId":                 "OrderingID",
+}
+
+// Validate checks the document against the constraints of the Push API and
+// returns every problem found as ValidationErrors, or nil if it is valid.
+func Validate(d Document) error {
+	errs := ValidationErrors{}
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(d.DocumentID) == 0 {
+		add("documentId", "the documentId is required")
+	} else if !isAbsoluteURI(d.DocumentID) {
+		add("documentId", "%q is not a valid absolute URI", d.DocumentID)
+	}
+	if len(d.ParentID) != 0 && !isAbsoluteURI(d.ParentID) {
+		add("parentId", "%q is not a valid absolute URI", d.ParentID)
+	}
+	if len(d.ClickableURI) != 0 && !isAbsoluteURI(d.ClickableURI) {
+		add("clickableUri", "%q is not a valid absolute URI", d.ClickableURI)
+	}
+
+	if len(d.Data) != 0 && len(d.CompressedBinaryData) != 0 {
+		add("data", "data and compressedBinaryData cannot both be set")
+	}
+	switch d.CompressionType {
+	case "", CompressionUncompressed, CompressionDeflate, CompressionGzip, CompressionZlib:
+	default:
+		add("compressionType", "invalid compression type %q", d.CompressionType)
+	}
+	if len(d.CompressionType) != 0 && len(d.CompressedBinaryData) == 0 {
+		add("compressionType", "the compression type is set without compressedBinaryData")
+	}
+
+	names := make([]string, 0, len(d.Fields))
+	for name := range d.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := "fields." + name
+		if typed, ok := typedKeys[name]; ok {
+			if len(typed) == 0 {
+				add(field, "%s is reserved", name)
+			} else {
+				add(field, "%s is reserved, set Document.%s instead", name, typed)
+			}
+			continue
+		}
+		if conflictsWithTypedField(d, name) {
+			add(field, "%s is also set by the typed field of the document", name)
+			continue
+		}
+		if len(name) > MaxFieldNameLength {
+			add(field, "the field name is longer than %d characters", MaxFieldNameLength)
+		}
+		if !fieldNamePattern.MatchString(name) {
+			add(field, "field names must start with a lowercase letter and only contain lowercase letters, digits and underscores")
+		}
+	}
+
+	errs = append(errs, validatePermissions(d.Permissions)...)
+
+	if marshalled, err := json.Marshal(d); err != nil {
+		add("", "the document cannot be serialized: %v", err)
+	} else if len(marshalled) > MaxDocumentSize {
+		add("", "the document is %d bytes, larger than %d bytes", len(marshalled), MaxDocumentSize)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validatePermissions(levels []PermissionLevel) ValidationErrors {
+	errs := ValidationErrors{}
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, level := range levels {
+		levelField := fmt.Sprintf("permissions[%d]", i)
+		if len(level.PermissionSets) == 0 {
+			add(levelField, "a permission level needs at least one permission set")
+		}
+
+		for j, set := range level.PermissionSets {
+			setField := fmt.Sprintf("%s.permissionSets[%d]", levelField, j)
+			if !set.AllowAnonymous && len(set.AllowedPermissions) == 0 {
+				add(setField, "the permission set allows nobody, allow anonymous or at least one identity")
+			}
+
+			for k, p := range set.AllowedPermissions {
+				errs = append(errs, validatePermission(fmt.Sprintf("%s.allowedPermissions[%d]", setField, k), p)...)
+			}
+			for k, p := range set.DeniedPermissions {
+				errs = append(errs, validatePermission(fmt.Sprintf("%s.deniedPermissions[%d]", setField, k), p)...)
+			}
+		}
+	}
+	return errs
+}
+
+func validatePermission(field string, p Permission) ValidationErrors {
+	errs := ValidationErrors{}
+	if len(p.Identity) == 0 {
+		errs = append(errs, &ValidationError{Field: field + ".identity", Message: "the identity is required"})
+	}
+
+	switch p.IdentityType {
+	case PermissionIdentityUser, PermissionIdentityGroup, PermissionIdentityVirtualGroup, PermissionIdentityUnknown:
+	default:
+		errs = append(errs, &ValidationError{Field: field + ".identityType", Message: fmt.Sprintf("invalid identity type %q", p.IdentityType)})
+	}
+	return errs
+}
+
+func conflictsWithTypedField(d Document, name string) bool {
+	switch name {
+	case keyTitle:
+		return len(d.Title) != 0
+	case keyAuthor:
+		return len(d.Author) != 0
+	case keyData:
+		return len(d.Data) != 0
+	case keyDate:
+		return !d.Date.IsZero()
+	}
+	return false
+}
+
+func isAbsoluteURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}
+
+// Validate checks that the ID of the deleted document is an absolute URI
+func (d DeletedDocument) Validate() error {
+	if len(d.DocumentID) == 0 {
+		return ValidationErrors{{Field: "documentId", Message: "the documentId is required"}}
+	}
+	if !isAbsoluteURI(d.DocumentID) {
+		return ValidationErrors{{Field: "documentId", Message: fmt.Sprintf("%q is not a valid absolute URI", d.DocumentID)}}
+	}
+	return nil
+}
+
+// validateBatch validates every document of the batch, prefixing the fields
+// of the errors with the position of the document
+func validateBatch(b Batch) error {
+	errs := ValidationErrors{}
+	for i, d := range b.AddOrUpdate {
+		if err := Validate(d); err != nil {
+			for _, e := range err.(ValidationErrors) {
+				field := fmt.Sprintf("addOrUpdate[%d]", i)
+				if len(e.Field) != 0 {
+					field += "." + e.Field
+				}
+				errs = append(errs, &ValidationError{Field: field, Message: e.Message})
+			}
+		}
+	}
+	for i, d := range b.Delete {
+		if err := d.Validate(); err != nil {
+			for _, e := range err.(ValidationErrors) {
+				errs = append(errs, &ValidationError{Field: fmt.Sprintf("delete[%d].%s", i, e.Field), Message: e.Message})
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
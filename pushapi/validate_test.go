@@ -0,0 +1,174 @@
+package pushapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+func TestValidate(t *testing.T) {
+	valid := pushapi.Document{
+		DocumentID: "https://example.com/doc",
+		Data:       "content",
+		Fields:     map[string]interface{}{"my_field2": "value"},
+	}
+	if err := pushapi.Validate(valid); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	invalid := pushapi.Document{
+		DocumentID:           "not a uri",
+		Data:                 "content",
+		CompressedBinaryData: "eJwLAQ==",
+		Fields:               map[string]interface{}{"MyField": "value", "permissions": "everyone"},
+		Permissions:          pushapi.SimplePermissions(false, nil, nil),
+	}
+	err := pushapi.Validate(invalid)
+	errs, ok := err.(pushapi.ValidationErrors)
+	if !ok {
+		t.Fatalf("unexpected error type.  expected %T, actual %T", pushapi.ValidationErrors{}, err)
+	}
+
+	expectedFields := []string{
+		"documentId",
+		"data",
+		"fields.MyField",
+		"fields.permissions",
+		"permissions[0].permissionSets[0]",
+	}
+	if len(errs) != len(expectedFields) {
+		t.Fatalf("unexpected errors.  expected %v, actual %v", expectedFields, errs)
+	}
+	for i, field := range expectedFields {
+		if errs[i].Field != field {
+			t.Errorf("unexpected field.  expected %v, actual %v", field, errs[i].Field)
+		}
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	valid := func() pushapi.Document {
+		return pushapi.Document{DocumentID: "https://example.com/doc"}
+	}
+	permission := func(p pushapi.Permission) []pushapi.PermissionLevel {
+		return []pushapi.PermissionLevel{{PermissionSets: []pushapi.PermissionSet{{AllowedPermissions: []pushapi.Permission{p}}}}}
+	}
+
+	tests := []struct {
+		name   string
+		change func(d *pushapi.Document)
+		field  string
+	}{
+		{"missing documentId", func(d *pushapi.Document) { d.DocumentID = "" }, "documentId"},
+		{"relative documentId", func(d *pushapi.Document) { d.DocumentID = "/doc" }, "documentId"},
+		{"relative parentId", func(d *pushapi.Document) { d.ParentID = "parent" }, "parentId"},
+		{"relative clickableUri", func(d *pushapi.Document) { d.ClickableURI = "doc.html" }, "clickableUri"},
+		{"data and compressedBinaryData", func(d *pushapi.Document) {
+			d.Data = "content"
+			d.CompressedBinaryData = "eJwLAQ=="
+		}, "data"},
+		{"invalid compression type", func(d *pushapi.Document) {
+			d.CompressedBinaryData = "eJwLAQ=="
+			d.CompressionType = "LZMA"
+		}, "compressionType"},
+		{"compression type without data", func(d *pushapi.Document) { d.CompressionType = pushapi.CompressionZlib }, "compressionType"},
+		{"typed key", func(d *pushapi.Document) { d.Fields = map[string]interface{}{"clickableUri": "https://example.com"} }, "fields.clickableUri"},
+		{"reserved key", func(d *pushapi.Document) { d.Fields = map[string]interface{}{"compressedBinaryDataFileId": "file1"} }, "fields.compressedBinaryDataFileId"},
+		{"conflict with typed field", func(d *pushapi.Document) {
+			d.Title = "A title"
+			d.Fields = map[string]interface{}{"title": "Another title"}
+		}, "fields.title"},
+		{"long field name", func(d *pushapi.Document) {
+			d.Fields = map[string]interface{}{strings.Repeat("a", pushapi.MaxFieldNameLength+1): "value"}
+		}, "fields." + strings.Repeat("a", pushapi.MaxFieldNameLength+1)},
+		{"invalid field name", func(d *pushapi.Document) { d.Fields = map[string]interface{}{"my-field": "value"} }, "fields.my-field"},
+		{"empty permission level", func(d *pushapi.Document) {
+			d.Permissions = []pushapi.PermissionLevel{{}}
+		}, "permissions[0]"},
+		{"permission set allowing nobody", func(d *pushapi.Document) {
+			d.Permissions = []pushapi.PermissionLevel{{PermissionSets: []pushapi.PermissionSet{{}}}}
+		}, "permissions[0].permissionSets[0]"},
+		{"permission without identity", func(d *pushapi.Document) {
+			d.Permissions = permission(pushapi.Permission{IdentityType: pushapi.PermissionIdentityUser})
+		}, "permissions[0].permissionSets[0].allowedPermissions[0].identity"},
+		{"invalid identity type", func(d *pushapi.Document) {
+			d.Permissions = permission(pushapi.Permission{Identity: "a@example.com", IdentityType: "Robot"})
+		}, "permissions[0].permissionSets[0].allowedPermissions[0].identityType"},
+	}
+	for _, test := range tests {
+		d := valid()
+		test.change(&d)
+		err := pushapi.Validate(d)
+		errs, ok := err.(pushapi.ValidationErrors)
+		if !ok || len(errs) != 1 {
+			t.Errorf("%s: unexpected errors.  expected a single error on %v, actual %v", test.name, test.field, err)
+			continue
+		}
+		if errs[0].Field != test.field {
+			t.Errorf("%s: unexpected field.  expected %v, actual %v", test.name, test.field, errs[0].Field)
+		}
+	}
+}
+
+func validatingClient(t *testing.T, server *fakePushAPI, validate bool) pushapi.Client {
+	c, err := pushapi.NewClient(pushapi.Config{
+		Endpoint:         server.URL + "/",
+		PlatformEndpoint: server.URL + "/platform/",
+		OrganizationID:   "myorg",
+		APIKey:           "key",
+		Validate:         validate,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return c
+}
+
+func TestValidateModePushDocumentSize(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	large := pushapi.Document{DocumentID: "https://example.com/large", Data: strings.Repeat("a", pushapi.MaxPushDocumentSize)}
+	if err := pushapi.Validate(large); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	// The document is valid, but too large to be sent alone
+	_, err := validatingClient(t, server, true).PushDocument(large, "mysource")
+	if _, ok := err.(pushapi.ValidationErrors); !ok {
+		t.Fatalf("unexpected error.  expected %T, actual %v", pushapi.ValidationErrors{}, err)
+	}
+	if len(server.requests) != 0 {
+		t.Fatalf("unexpected requests.  expected none, actual %v", server.requests)
+	}
+
+	// The limit is left to the Push API without the validate mode
+	if _, err := validatingClient(t, server, false).PushDocument(large, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if len(server.requests) != 1 {
+		t.Errorf("unexpected requests.  expected %v, actual %v", 1, server.requests)
+	}
+}
+
+func TestValidateModeBatchPush(t *testing.T) {
+	server := newFakePushAPI()
+	defer server.Close()
+
+	batch := pushapi.Batch{
+		AddOrUpdate: []pushapi.Document{{DocumentID: "https://example.com/a"}, {DocumentID: "b"}},
+		Delete:      []pushapi.DeletedDocument{{DocumentID: "c"}},
+	}
+	err := validatingClient(t, server, true).BatchPush(batch, "mysource")
+	errs, ok := err.(pushapi.ValidationErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("unexpected errors.  expected 2 validation errors, actual %v", err)
+	}
+	if errs[0].Field != "addOrUpdate[1].documentId" || errs[1].Field != "delete[0].documentId" {
+		t.Errorf("unexpected fields.  expected %v and %v, actual %v", "addOrUpdate[1].documentId", "delete[0].documentId", errs)
+	}
+	if len(server.requests) != 0 {
+		t.Errorf("unexpected requests.  expected none, actual %v", server.requests)
+	}
+}
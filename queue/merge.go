@@ -0,0 +1,158 @@
+package queue
+
+import (
+	"os"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// maxMergedOperations is the maximum number of queued operations merged in a
+// single request
+const maxMergedOperations = 1000
+
+// next returns the operation to send for the head of the queue, and the
+// sequence numbers of the queued operations it covers. With MergeBatches, the
+// batches following the head are merged into it while they target the same
+// source or provider, fit in MaxBatchSize and do not touch an item already in
+// the merged batch, so that the order of the operations on an item is kept.
+func (q *Queue) next(head pending, single bool) (*Operation, []uint64, error) {
+	op, err := q.read(head.seq)
+	if err != nil {
+		return nil, nil, err
+	}
+	seqs := []uint64{head.seq}
+	if single || !q.options.MergeBatches || !mergeable(op.Type) {
+		return op, seqs, nil
+	}
+
+	q.mu.Lock()
+	end := len(q.pending)
+	if end > maxMergedOperations {
+		end = maxMergedOperations
+	}
+	candidates := append([]pending(nil), q.pending[1:end]...)
+	q.mu.Unlock()
+
+	merged := op.copy()
+	size := q.size(head.seq)
+	ids := map[string]bool{}
+	for _, id := range op.itemIDs() {
+		ids[id] = true
+	}
+
+	for _, p := range candidates {
+		candidate, err := q.read(p.seq)
+		if err != nil || !merged.sameTarget(candidate) {
+			break
+		}
+		candidateSize := q.size(p.seq)
+		if size+candidateSize > q.options.MaxBatchSize {
+			break
+		}
+
+		candidateIDs := candidate.itemIDs()
+		overlap := false
+		for _, id := range candidateIDs {
+			if ids[id] {
+				overlap = true
+			}
+		}
+		if overlap {
+			break
+		}
+
+		for _, id := range candidateIDs {
+			ids[id] = true
+		}
+		merged.merge(candidate)
+		size += candidateSize
+		seqs = append(seqs, p.seq)
+	}
+	return merged, seqs, nil
+}
+
+// size returns the size of the persisted operation, close to the size of its
+// payload
+func (q *Queue) size(seq uint64) int {
+	info, err := os.Stat(q.path(seq))
+	if err != nil {
+		return 0
+	}
+	return int(info.Size())
+}
+
+func mergeable(t OperationType) bool {
+	return t == OperationBatchPush || t == OperationPushIdentityBatch
+}
+
+// copy returns a copy of the operation whose batches can be appended to
+func (op *Operation) copy() *Operation {
+	c := *op
+	if op.Batch != nil {
+		c.Batch = &pushapi.Batch{
+			AddOrUpdate: append([]pushapi.Document(nil), op.Batch.AddOrUpdate...),
+			Delete:      append([]pushapi.DeletedDocument(nil), op.Batch.Delete...),
+		}
+	}
+	if op.IdentityBatch != nil {
+		c.IdentityBatch = &pushapi.IdentityBatch{
+			Members:  append([]pushapi.Identity(nil), op.IdentityBatch.Members...),
+			Mappings: append([]pushapi.Identity(nil), op.IdentityBatch.Mappings...),
+			Deleted:  append([]pushapi.Identity(nil), op.IdentityBatch.Deleted...),
+		}
+	}
+	return &c
+}
+
+// sameTarget returns true if other can be merged into the operation
+func (op *Operation) sameTarget(other *Operation) bool {
+	if other.Type != op.Type {
+		return false
+	}
+	switch op.Type {
+	case OperationBatchPush:
+		return other.SourceID == op.SourceID && other.Batch != nil
+	case OperationPushIdentityBatch:
+		return other.ProviderID == op.ProviderID && other.MaxItems == op.MaxItems && other.IdentityBatch != nil
+	}
+	return false
+}
+
+// itemIDs returns the IDs of the documents or identities of the batch
+func (op *Operation) itemIDs() []string {
+	ids := []string{}
+	if op.Batch != nil {
+		for _, d := range op.Batch.AddOrUpdate {
+			ids = append(ids, d.DocumentID)
+		}
+		for _, d := range op.Batch.Delete {
+			ids = append(ids, d.DocumentID)
+		}
+	}
+	if op.IdentityBatch != nil {
+		for _, identities := range [][]pushapi.Identity{op.IdentityBatch.Members, op.IdentityBatch.Mappings, op.IdentityBatch.Deleted} {
+			for _, i := range identities {
+				ids = append(ids, i.Identity.Name)
+			}
+		}
+	}
+	return ids
+}
+
+// merge appends the batch of other to the batch of the operation. The merged
+// batch is sent with the latest ordering ID, which is higher than the ones of
+// the items it replaces.
+func (op *Operation) merge(other *Operation) {
+	if other.OrderingID > op.OrderingID {
+		op.OrderingID = other.OrderingID
+	}
+	if op.Batch != nil {
+		op.Batch.AddOrUpdate = append(op.Batch.AddOrUpdate, other.Batch.AddOrUpdate...)
+		op.Batch.Delete = append(op.Batch.Delete, other.Batch.Delete...)
+	}
+	if op.IdentityBatch != nil {
+		op.IdentityBatch.Members = append(op.IdentityBatch.Members, other.IdentityBatch.Members...)
+		op.IdentityBatch.Mappings = append(op.IdentityBatch.Mappings, other.IdentityBatch.Mappings...)
+		op.IdentityBatch.Deleted = append(op.IdentityBatch.Deleted, other.IdentityBatch.Deleted...)
+	}
+}
@@ -0,0 +1,130 @@
+package queue
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// OperationType is the pushapi.Client method an operation calls
+type OperationType string
+
+const (
+	// OperationPushDocument calls PushDocument
+	OperationPushDocument OperationType = "pushDocument"
+	// OperationDeleteDocument calls DeleteDocumentWithOptions
+	OperationDeleteDocument OperationType = "deleteDocument"
+	// OperationDeleteOlderThan calls DeleteOlderThan
+	OperationDeleteOlderThan OperationType = "deleteOlderThan"
+	// OperationBatchPush calls BatchPush
+	OperationBatchPush OperationType = "batchPush"
+	// OperationSetSourceStatus calls SetSourceStatus
+	OperationSetSourceStatus OperationType = "setSourceStatus"
+	// OperationPushIdentity calls PushIdentity
+	OperationPushIdentity OperationType = "pushIdentity"
+	// OperationDeleteIdentity calls DeleteIdentity
+	OperationDeleteIdentity OperationType = "deleteIdentity"
+	// OperationPushIdentityBatch calls PushIdentityBatch
+	OperationPushIdentityBatch OperationType = "pushIdentityBatch"
+	// OperationDeleteIdentitiesOlderThan calls DeleteIdentitiesOlderThan
+	OperationDeleteIdentitiesOlderThan OperationType = "deleteIdentitiesOlderThan"
+	// OperationRefreshSecurityProvider calls RefreshSecurityProvider
+	OperationRefreshSecurityProvider OperationType = "refreshSecurityProvider"
+)
+
+// Operation is a pushapi.Client call persisted in the queue
+type Operation struct {
+	// Seq is the position of the operation in the queue
+	Seq uint64 `json:"seq"`
+	// Type is the method called
+	Type OperationType `json:"type"`
+	// EnqueuedAt is when the operation was added to the queue
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+
+	SourceID       string                 `json:"sourceId,omitempty"`
+	ProviderID     string                 `json:"providerId,omitempty"`
+	DocumentID     string                 `json:"documentId,omitempty"`
+	OrderingID     int64                  `json:"orderingId,omitempty"`
+	DeleteChildren bool                   `json:"deleteChildren,omitempty"`
+	Status         pushapi.SourceStatus   `json:"status,omitempty"`
+	MaxItems       int                    `json:"maxItems,omitempty"`
+	Document       *pushapi.Document      `json:"document,omitempty"`
+	Batch          *pushapi.Batch         `json:"batch,omitempty"`
+	Identity       *pushapi.Identity      `json:"identity,omitempty"`
+	IdentityBatch  *pushapi.IdentityBatch `json:"identityBatch,omitempty"`
+}
+
+// apply calls the client method of the operation. The ordering IDs are not
+// part of the marshalled documents and batches, they are restored first.
+func (op Operation) apply(c pushapi.Client) error {
+	switch op.Type {
+	case OperationPushDocument:
+		if op.Document == nil {
+			return errors.New("the operation has no document")
+		}
+		d := *op.Document
+		d.OrderingID = op.OrderingID
+		_, err := c.PushDocument(d, op.SourceID)
+		return err
+	case OperationDeleteDocument:
+		return c.DeleteDocumentWithOptions(op.DocumentID, op.SourceID, pushapi.DeleteOptions{
+			OrderingID:     op.OrderingID,
+			DeleteChildren: op.DeleteChildren,
+		})
+	case OperationDeleteOlderThan:
+		return c.DeleteOlderThan(op.SourceID, op.OrderingID)
+	case OperationBatchPush:
+		if op.Batch == nil {
+			return errors.New("the operation has no batch")
+		}
+		b := *op.Batch
+		b.OrderingID = op.OrderingID
+		return c.BatchPush(b, op.SourceID)
+	case OperationSetSourceStatus:
+		return c.SetSourceStatus(op.SourceID, op.Status)
+	case OperationPushIdentity:
+		if op.Identity == nil {
+			return errors.New("the operation has no identity")
+		}
+		i := *op.Identity
+		i.OrderingID = op.OrderingID
+		return c.PushIdentity(i, op.ProviderID)
+	case OperationDeleteIdentity:
+		if op.Identity == nil {
+			return errors.New("the operation has no identity")
+		}
+		i := *op.Identity
+		i.OrderingID = op.OrderingID
+		return c.DeleteIdentity(i, op.ProviderID)
+	case OperationPushIdentityBatch:
+		if op.IdentityBatch == nil {
+			return errors.New("the operation has no identity batch")
+		}
+		return c.PushIdentityBatch(*op.IdentityBatch, op.ProviderID, pushapi.BatchOptions{
+			MaxItems:   op.MaxItems,
+			OrderingID: op.OrderingID,
+		})
+	case OperationDeleteIdentitiesOlderThan:
+		return c.DeleteIdentitiesOlderThan(op.ProviderID, op.OrderingID)
+	case OperationRefreshSecurityProvider:
+		return c.RefreshSecurityProvider(op.ProviderID)
+	}
+	return errors.New("unknown operation type " + string(op.Type))
+}
+
+// retryable returns true if the operation may succeed when sent again: the
+// network errors, the throttling and the errors of the service. Any other
+// error would be returned again, the operation is dropped.
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case *pushapi.APIError:
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	case *url.Error, net.Error:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,590 @@
+// Package queue provides a durable queue in front of a pushapi.Client. The
+// operations are written to disk before being acknowledged to the caller, and
+// removed only once the Push API accepted them, so that they survive a crash
+// and are replayed when the queue is opened again.
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+const (
+	// DefaultRetryDelay is the delay before the first retry of an operation
+	DefaultRetryDelay = time.Second
+	// DefaultMaxRetryDelay is the maximum delay between two retries
+	DefaultMaxRetryDelay = time.Minute
+
+	operationExt = ".op"
+	failedDir    = "failed"
+)
+
+// ErrClosed is returned by the operations of a closed queue
+var ErrClosed = errors.New("the queue is closed")
+
+// Options are the options of a queue
+type Options struct {
+	// RetryDelay is the delay before the first retry of a failed operation,
+	// doubled after each failure. DefaultRetryDelay if zero.
+	RetryDelay time.Duration
+	// MaxRetryDelay is the maximum delay between two retries,
+	// DefaultMaxRetryDelay if zero
+	MaxRetryDelay time.Duration
+	// OnDrop is called when an operation fails with an error that is not
+	// worth retrying, anything but a network error, a throttling or an error
+	// of the service. The operation is moved to the failed directory of the
+	// queue and the next operations are sent.
+	OnDrop func(op Operation, err error)
+	// MergeBatches merges the consecutive batches of a source, or of a
+	// security provider, in a single request. A merged request rejected by
+	// the Push API is sent again one operation at a time.
+	MergeBatches bool
+	// MaxBatchSize is the maximum size in bytes of merged batches,
+	// pushapi.DefaultMaxBatchSize if zero
+	MaxBatchSize int
+}
+
+// Stats describe the state of the queue
+type Stats struct {
+	// Depth is the number of operations waiting to be sent
+	Depth int `json:"depth"`
+	// Oldest is when the oldest waiting operation was enqueued, zero if the
+	// queue is empty
+	Oldest time.Time `json:"oldest,omitempty"`
+	// Age is how long the oldest waiting operation has been waiting
+	Age time.Duration `json:"age"`
+	// Sent is the number of operations accepted by the Push API since the
+	// queue was opened
+	Sent int64 `json:"sent"`
+	// Dropped is the number of operations rejected since the queue was opened
+	Dropped int64 `json:"dropped"`
+	// Retries is the number of failed attempts since the queue was opened
+	Retries int64 `json:"retries"`
+	// LastError is the last error returned by the Push API, if any
+	LastError string `json:"lastError,omitempty"`
+}
+
+// pending is an operation waiting in the queue, only its position and age
+// are kept in memory
+type pending struct {
+	seq        uint64
+	enqueuedAt time.Time
+}
+
+// Queue is a durable queue of Push API operations. It implements
+// pushapi.Client: every method persists the operation and returns once it is
+// on disk, a single worker then sends the operations in order through the
+// wrapped client, retrying them until they are accepted. Queue is safe for
+// concurrent use.
+type Queue struct {
+	dir     string
+	client  pushapi.Client
+	options Options
+
+	mu      sync.Mutex
+	changed *sync.Cond
+	pending []pending
+	nextSeq uint64
+	stats   Stats
+	closed  bool
+	closing chan struct{}
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+// Open opens the queue stored in dir, creating it if needed, and starts
+// sending its operations through c, beginning with the ones left by a
+// previous run. Call Close once done.
+func Open(dir string, c pushapi.Client, o Options) (*Queue, error) {
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = DefaultRetryDelay
+	}
+	if o.MaxRetryDelay <= 0 {
+		o.MaxRetryDelay = DefaultMaxRetryDelay
+	}
+	if o.MaxBatchSize <= 0 {
+		o.MaxBatchSize = pushapi.DefaultMaxBatchSize
+	}
+	if err := os.MkdirAll(filepath.Join(dir, failedDir), 0755); err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		dir:     dir,
+		client:  c,
+		options: o,
+		nextSeq: 1,
+		closing: make(chan struct{}),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	q.changed = sync.NewCond(&q.mu)
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	go q.run()
+	q.wake()
+	return q, nil
+}
+
+// load reads the operations left on disk, the partially written ones are
+// discarded since they were never acknowledged
+func (q *Queue) load() error {
+	files, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			if err := os.Remove(filepath.Join(q.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.IsDir() || !strings.HasSuffix(name, operationExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, operationExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+		op, err := q.read(seq)
+		if err != nil {
+			// The operation cannot be sent, drop it like run does
+			q.drop(seq, fmt.Errorf("invalid queued operation %s: %v", name, err))
+			continue
+		}
+
+		q.pending = append(q.pending, pending{seq: seq, enqueuedAt: op.EnqueuedAt})
+	}
+
+	sort.Slice(q.pending, func(i, j int) bool { return q.pending[i].seq < q.pending[j].seq })
+	return nil
+}
+
+// drop moves an operation read from disk, and not queued, to the failed
+// directory
+func (q *Queue) drop(seq uint64, err error) {
+	q.stats.Dropped++
+	q.stats.LastError = err.Error()
+	if renameErr := os.Rename(q.path(seq), filepath.Join(q.dir, failedDir, filepath.Base(q.path(seq)))); renameErr != nil {
+		q.stats.LastError = renameErr.Error()
+	}
+	if q.options.OnDrop != nil {
+		q.options.OnDrop(Operation{Seq: seq}, err)
+	}
+}
+
+func (q *Queue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, operationExt))
+}
+
+func (q *Queue) read(seq uint64) (*Operation, error) {
+	content, err := ioutil.ReadFile(q.path(seq))
+	if err != nil {
+		return nil, err
+	}
+	op := &Operation{}
+	if err := json.Unmarshal(content, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// enqueue writes the operation to disk and adds it to the queue. The file is
+// synced then renamed, so that a crash never leaves a partial operation.
+func (q *Queue) enqueue(op Operation) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	op.Seq = q.nextSeq
+	op.EnqueuedAt = time.Now()
+	content, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	path := q.path(op.Seq)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	q.nextSeq++
+	q.pending = append(q.pending, pending{seq: op.Seq, enqueuedAt: op.EnqueuedAt})
+	q.wake()
+	return nil
+}
+
+// wake tells the worker there is something to send
+func (q *Queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the operations in order until the queue is closed
+func (q *Queue) run() {
+	defer close(q.done)
+
+	delay := q.options.RetryDelay
+	// After a merged request is rejected, the operations it covered are sent
+	// one by one, up to this sequence number
+	var singleUntil uint64
+	for {
+		q.mu.Lock()
+		var next pending
+		empty := len(q.pending) == 0
+		if !empty {
+			next = q.pending[0]
+		}
+		q.mu.Unlock()
+
+		if empty {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.closing:
+				return
+			}
+		}
+
+		op, seqs, err := q.next(next, next.seq <= singleUntil)
+		invalid := err != nil
+		if invalid {
+			op = &Operation{Seq: next.seq, EnqueuedAt: next.enqueuedAt}
+		} else {
+			err = op.apply(q.client)
+		}
+
+		switch {
+		case err == nil:
+			for _, seq := range seqs {
+				q.remove(seq, nil)
+			}
+			delay = q.options.RetryDelay
+		case !invalid && retryable(err):
+			q.mu.Lock()
+			q.stats.Retries++
+			q.stats.LastError = err.Error()
+			q.mu.Unlock()
+
+			select {
+			case <-time.After(delay):
+			case <-q.closing:
+				return
+			}
+			if delay *= 2; delay > q.options.MaxRetryDelay {
+				delay = q.options.MaxRetryDelay
+			}
+		case len(seqs) > 1:
+			// One of the merged operations is invalid, send them one by one so
+			// that only the invalid one is dropped
+			singleUntil = seqs[len(seqs)-1]
+		default:
+			q.remove(next.seq, err)
+			if q.options.OnDrop != nil {
+				q.options.OnDrop(*op, err)
+			}
+			delay = q.options.RetryDelay
+		}
+
+		select {
+		case <-q.closing:
+			return
+		default:
+		}
+	}
+}
+
+// remove takes the operation out of the queue, the dropped operations are
+// moved to the failed directory
+func (q *Queue) remove(seq uint64, dropErr error) {
+	var err error
+	if dropErr == nil {
+		err = os.Remove(q.path(seq))
+	} else {
+		err = os.Rename(q.path(seq), filepath.Join(q.dir, failedDir, filepath.Base(q.path(seq))))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err != nil && !os.IsNotExist(err) {
+		// The operation would be sent again after a restart, keep going
+		q.stats.LastError = err.Error()
+	}
+	if dropErr == nil {
+		q.stats.Sent++
+	} else {
+		q.stats.Dropped++
+		q.stats.LastError = dropErr.Error()
+	}
+	q.pending = q.pending[1:]
+	q.changed.Broadcast()
+}
+
+// Stats returns the current state of the queue
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	stats.Depth = len(q.pending)
+	if len(q.pending) != 0 {
+		stats.Oldest = q.pending[0].enqueuedAt
+		stats.Age = time.Since(stats.Oldest)
+	}
+	return stats
+}
+
+// Drain blocks until every operation was sent or dropped. It returns
+// ErrClosed if the queue is closed first.
+func (q *Queue) Drain() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) != 0 && !q.closed {
+		q.changed.Wait()
+	}
+	if len(q.pending) != 0 {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close stops the worker once the operation being sent is done. The
+// operations left are kept on disk and sent when the queue is opened again.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.closing)
+	q.changed.Broadcast()
+	q.mu.Unlock()
+
+	<-q.done
+	return nil
+}
+
+// orderingID returns the ordering ID of the operation, generated when it is
+// enqueued so that replayed operations keep their order
+func orderingID(orderingID int64) int64 {
+	if orderingID == 0 {
+		return pushapi.NewOrderingID()
+	}
+	return orderingID
+}
+
+// PushDocument enqueues the document, the returned response is always empty
+func (q *Queue) PushDocument(d pushapi.Document, sourceID string) (string, error) {
+	if len(sourceID) == 0 {
+		return "", errors.New("You need a sourceID")
+	}
+	if len(d.DocumentID) == 0 {
+		return "", errors.New("You need to provide a documentID")
+	}
+
+	return "", q.enqueue(Operation{
+		Type:       OperationPushDocument,
+		SourceID:   sourceID,
+		OrderingID: orderingID(d.OrderingID),
+		Document:   &d,
+	})
+}
+
+// DeleteDocument enqueues the deletion of the document
+func (q *Queue) DeleteDocument(documentID, sourceID string) error {
+	return q.DeleteDocumentWithOptions(documentID, sourceID, pushapi.DeleteOptions{})
+}
+
+// DeleteDocumentWithOptions enqueues the deletion of the document
+func (q *Queue) DeleteDocumentWithOptions(documentID, sourceID string, o pushapi.DeleteOptions) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	if len(documentID) == 0 {
+		return errors.New("You need a documentID")
+	}
+
+	return q.enqueue(Operation{
+		Type:           OperationDeleteDocument,
+		SourceID:       sourceID,
+		DocumentID:     documentID,
+		OrderingID:     orderingID(o.OrderingID),
+		DeleteChildren: o.DeleteChildren,
+	})
+}
+
+// DeleteOlderThan enqueues the deletion of the documents older than
+// orderingID
+func (q *Queue) DeleteOlderThan(sourceID string, orderingID int64) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	return q.enqueue(Operation{
+		Type:       OperationDeleteOlderThan,
+		SourceID:   sourceID,
+		OrderingID: orderingID,
+	})
+}
+
+// BatchPush enqueues the batch
+func (q *Queue) BatchPush(b pushapi.Batch, sourceID string) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	for _, d := range b.AddOrUpdate {
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need to provide a documentID")
+		}
+	}
+	for _, d := range b.Delete {
+		if len(d.DocumentID) == 0 {
+			return errors.New("You need a documentID")
+		}
+	}
+
+	return q.enqueue(Operation{
+		Type:       OperationBatchPush,
+		SourceID:   sourceID,
+		OrderingID: orderingID(b.OrderingID),
+		Batch:      &b,
+	})
+}
+
+// SetSourceStatus enqueues the status change
+func (q *Queue) SetSourceStatus(sourceID string, status pushapi.SourceStatus) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	if err := status.Validate(); err != nil {
+		return err
+	}
+
+	return q.enqueue(Operation{
+		Type:     OperationSetSourceStatus,
+		SourceID: sourceID,
+		Status:   status,
+	})
+}
+
+// PushIdentity enqueues the identity
+func (q *Queue) PushIdentity(i pushapi.Identity, providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	if err := i.Validate(); err != nil {
+		return err
+	}
+
+	return q.enqueue(Operation{
+		Type:       OperationPushIdentity,
+		ProviderID: providerID,
+		OrderingID: orderingID(i.OrderingID),
+		Identity:   &i,
+	})
+}
+
+// DeleteIdentity enqueues the deletion of the identity
+func (q *Queue) DeleteIdentity(i pushapi.Identity, providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	if err := (pushapi.Identity{Identity: i.Identity}).Validate(); err != nil {
+		return err
+	}
+
+	return q.enqueue(Operation{
+		Type:       OperationDeleteIdentity,
+		ProviderID: providerID,
+		OrderingID: orderingID(i.OrderingID),
+		Identity:   &pushapi.Identity{Identity: i.Identity},
+	})
+}
+
+// PushIdentityBatch enqueues the batch, only the MaxItems and OrderingID
+// options are kept
+func (q *Queue) PushIdentityBatch(b pushapi.IdentityBatch, providerID string, o pushapi.BatchOptions) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+
+	return q.enqueue(Operation{
+		Type:          OperationPushIdentityBatch,
+		ProviderID:    providerID,
+		OrderingID:    orderingID(o.OrderingID),
+		MaxItems:      o.MaxItems,
+		IdentityBatch: &b,
+	})
+}
+
+// DeleteIdentitiesOlderThan enqueues the deletion of the identities older
+// than orderingID
+func (q *Queue) DeleteIdentitiesOlderThan(providerID string, orderingID int64) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	return q.enqueue(Operation{
+		Type:       OperationDeleteIdentitiesOlderThan,
+		ProviderID: providerID,
+		OrderingID: orderingID,
+	})
+}
+
+// RefreshSecurityProvider enqueues the refresh of the security provider
+func (q *Queue) RefreshSecurityProvider(providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+
+	return q.enqueue(Operation{
+		Type:       OperationRefreshSecurityProvider,
+		ProviderID: providerID,
+	})
+}
@@ -0,0 +1,443 @@
+package queue_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/queue"
+)
+
+var _ pushapi.Client = &queue.Queue{}
+
+// fakeClient records the documents pushed, failing with the queued errors
+// first
+type fakeClient struct {
+	pushapi.Client
+	mu      sync.Mutex
+	errors  []error
+	pushed  []pushapi.Document
+	batches []pushapi.Batch
+	// unblock, if set, is waited for by BatchPush
+	unblock chan struct{}
+}
+
+func (c *fakeClient) PushDocument(d pushapi.Document, sourceID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errors) != 0 {
+		err := c.errors[0]
+		c.errors = c.errors[1:]
+		return "", err
+	}
+	c.pushed = append(c.pushed, d)
+	return "", nil
+}
+
+func (c *fakeClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	if c.unblock != nil {
+		<-c.unblock
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errors) != 0 {
+		err := c.errors[0]
+		c.errors = c.errors[1:]
+		return err
+	}
+	c.batches = append(c.batches, b)
+	return nil
+}
+
+func (c *fakeClient) documentIDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := []string{}
+	for _, d := range c.pushed {
+		ids = append(ids, d.DocumentID)
+	}
+	return ids
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return dir
+}
+
+func TestQueueRetries(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	client := &fakeClient{errors: []error{&pushapi.APIError{StatusCode: 503}}}
+	q, err := queue.Open(dir, client, queue.Options{RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	if _, err := q.PushDocument(pushapi.Document{DocumentID: "file://a"}, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	stats := q.Stats()
+	if stats.Depth != 0 || stats.Sent != 1 || stats.Retries != 1 {
+		t.Errorf("unexpected stats.  expected depth 0, 1 sent and 1 retry, actual %+v", stats)
+	}
+	if ids := client.documentIDs(); len(ids) != 1 || ids[0] != "file://a" {
+		t.Errorf("unexpected documents.  expected %v, actual %v", []string{"file://a"}, ids)
+	}
+	if client.pushed[0].OrderingID == 0 {
+		t.Errorf("expected an ordering ID to be assigned when enqueued")
+	}
+}
+
+func TestQueueReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// The Push API is unavailable for the first run
+	unavailable := &fakeClient{}
+	for i := 0; i < 100; i++ {
+		unavailable.errors = append(unavailable.errors, &pushapi.APIError{StatusCode: 503})
+	}
+	q, err := queue.Open(dir, unavailable, queue.Options{RetryDelay: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	for _, id := range []string{"file://a", "file://b"} {
+		if _, err := q.PushDocument(pushapi.Document{DocumentID: id}, "mysource"); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	if depth := q.Stats().Depth; depth != 2 {
+		t.Errorf("unexpected depth.  expected %v, actual %v", 2, depth)
+	}
+	q.Close()
+
+	client := &fakeClient{}
+	q, err = queue.Open(dir, client, queue.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	ids := client.documentIDs()
+	if len(ids) != 2 || ids[0] != "file://a" || ids[1] != "file://b" {
+		t.Errorf("unexpected documents.  expected %v, actual %v", []string{"file://a", "file://b"}, ids)
+	}
+}
+
+func TestQueueDrop(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	client := &fakeClient{errors: []error{&pushapi.APIError{StatusCode: 400}}}
+	dropped := make(chan queue.Operation, 1)
+	q, err := queue.Open(dir, client, queue.Options{OnDrop: func(op queue.Operation, err error) {
+		dropped <- op
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	if _, err := q.PushDocument(pushapi.Document{DocumentID: "file://a"}, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	op := <-dropped
+	if op.Document == nil || op.Document.DocumentID != "file://a" {
+		t.Errorf("unexpected dropped operation.  expected %v, actual %+v", "file://a", op)
+	}
+	failed, _ := filepath.Glob(filepath.Join(dir, "failed", "*.op"))
+	if len(failed) != 1 {
+		t.Errorf("unexpected failed operations.  expected %v, actual %v", 1, len(failed))
+	}
+	if stats := q.Stats(); stats.Dropped != 1 {
+		t.Errorf("unexpected stats.  expected %v dropped, actual %+v", 1, stats)
+	}
+}
+
+func TestQueueDropsNonRetryableErrors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// A plain error, like an argument error of the client, is returned again
+	// on every attempt
+	client := &fakeClient{errors: []error{errors.New("You need a sourceID")}}
+	q, err := queue.Open(dir, client, queue.Options{RetryDelay: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	for _, id := range []string{"file://a", "file://b"} {
+		if _, err := q.PushDocument(pushapi.Document{DocumentID: id}, "mysource"); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if ids := client.documentIDs(); len(ids) != 1 || ids[0] != "file://b" {
+		t.Errorf("unexpected documents.  expected %v, actual %v", []string{"file://b"}, ids)
+	}
+	failed, _ := filepath.Glob(filepath.Join(dir, "failed", "*.op"))
+	if len(failed) != 1 {
+		t.Errorf("unexpected failed operations.  expected %v, actual %v", 1, len(failed))
+	}
+	if stats := q.Stats(); stats.Retries != 0 || stats.Dropped != 1 || stats.Sent != 1 {
+		t.Errorf("unexpected stats.  expected 1 dropped, 1 sent and no retry, actual %+v", stats)
+	}
+}
+
+func TestQueueRejectsInvalidOperations(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := queue.Open(dir, &fakeClient{}, queue.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	if err := q.SetSourceStatus("mysource", "SLEEPING"); err == nil {
+		t.Errorf("expected an error for an invalid status")
+	}
+	if err := q.BatchPush(pushapi.Batch{AddOrUpdate: []pushapi.Document{{}}}, "mysource"); err == nil {
+		t.Errorf("expected an error for a document without ID")
+	}
+	if depth := q.Stats().Depth; depth != 0 {
+		t.Errorf("unexpected depth.  expected %v, actual %v", 0, depth)
+	}
+}
+
+func TestQueueRetriesNetworkErrors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	client := &fakeClient{errors: []error{&url.Error{Op: "Put", URL: "https://push", Err: errors.New("connection reset")}}}
+	q, err := queue.Open(dir, client, queue.Options{RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	if _, err := q.PushDocument(pushapi.Document{DocumentID: "file://a"}, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats := q.Stats(); stats.Retries != 1 || stats.Sent != 1 {
+		t.Errorf("unexpected stats.  expected 1 retry and 1 sent, actual %+v", stats)
+	}
+}
+
+func TestQueueMergesBatches(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// The first attempt waits for every batch to be queued, and fails so that
+	// the retry sends them all
+	client := &fakeClient{
+		errors:  []error{&pushapi.APIError{StatusCode: 503}},
+		unblock: make(chan struct{}),
+	}
+	q, err := queue.Open(dir, client, queue.Options{RetryDelay: time.Millisecond, MergeBatches: true})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	batches := []struct {
+		sourceID string
+		batch    pushapi.Batch
+	}{
+		{"mysource", pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: "file://a"}}}},
+		{"mysource", pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: "file://b"}}}},
+		{"mysource", pushapi.Batch{Delete: []pushapi.DeletedDocument{{DocumentID: "file://c"}}}},
+		// Deleting a document of the merged batch starts a new batch
+		{"mysource", pushapi.Batch{Delete: []pushapi.DeletedDocument{{DocumentID: "file://a"}}}},
+		// So does another source
+		{"othersource", pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: "file://d"}}}},
+	}
+	for _, b := range batches {
+		if err := q.BatchPush(b.batch, b.sourceID); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	close(client.unblock)
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	if len(client.batches) != 3 {
+		t.Fatalf("unexpected batches.  expected %v, actual %+v", 3, client.batches)
+	}
+	if merged := client.batches[0]; len(merged.AddOrUpdate) != 2 || len(merged.Delete) != 1 {
+		t.Errorf("unexpected merged batch.  expected 2 documents and 1 deletion, actual %+v", merged)
+	}
+	if client.batches[0].OrderingID == 0 {
+		t.Errorf("expected the merged batch to have an ordering ID")
+	}
+	if stats := q.Stats(); stats.Sent != 5 || stats.Depth != 0 {
+		t.Errorf("unexpected stats.  expected 5 sent, actual %+v", stats)
+	}
+}
+
+func TestQueueSendsMergedBatchesOneByOneOnError(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	client := &fakeClient{
+		errors: []error{
+			&pushapi.APIError{StatusCode: 503},
+			&pushapi.APIError{StatusCode: 400},
+			&pushapi.APIError{StatusCode: 400},
+		},
+		unblock: make(chan struct{}),
+	}
+	q, err := queue.Open(dir, client, queue.Options{RetryDelay: time.Millisecond, MergeBatches: true})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	for _, id := range []string{"file://a", "file://b"} {
+		if err := q.BatchPush(pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: id}}}, "mysource"); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	close(client.unblock)
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	// Once both batches are queued, the merged batch and the first batch alone
+	// are rejected, the second one is sent
+	if len(client.batches) != 1 || client.batches[0].AddOrUpdate[0].DocumentID != "file://b" {
+		t.Errorf("unexpected batches.  expected %v, actual %+v", "file://b", client.batches)
+	}
+	if stats := q.Stats(); stats.Sent != 1 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats.  expected 1 sent and 1 dropped, actual %+v", stats)
+	}
+}
+
+// rejectingClient rejects the batches holding file://bad and records the
+// other ones
+type rejectingClient struct {
+	pushapi.Client
+	mu       sync.Mutex
+	rejected int
+	batches  []pushapi.Batch
+}
+
+func (c *rejectingClient) BatchPush(b pushapi.Batch, sourceID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range b.AddOrUpdate {
+		if d.DocumentID == "file://bad" {
+			c.rejected++
+			return &pushapi.APIError{StatusCode: 400}
+		}
+	}
+	c.batches = append(c.batches, b)
+	return nil
+}
+
+func TestQueueStaysOneByOneUntilTheRejectedBatch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// The batches are queued while the Push API is unavailable
+	unavailable := &fakeClient{errors: []error{&pushapi.APIError{StatusCode: 503}}}
+	q, err := queue.Open(dir, unavailable, queue.Options{RetryDelay: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	for _, id := range []string{"file://a", "file://b", "file://bad", "file://c"} {
+		if err := q.BatchPush(pushapi.Batch{AddOrUpdate: []pushapi.Document{{DocumentID: id}}}, "mysource"); err != nil {
+			t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+		}
+	}
+	q.Close()
+
+	client := &rejectingClient{}
+	q, err = queue.Open(dir, client, queue.Options{MergeBatches: true})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	// Only the merged batch and the bad batch alone are rejected
+	if client.rejected != 2 {
+		t.Errorf("unexpected rejected requests.  expected %v, actual %v", 2, client.rejected)
+	}
+	if len(client.batches) != 3 {
+		t.Errorf("unexpected batches.  expected %v, actual %+v", 3, client.batches)
+	}
+	if stats := q.Stats(); stats.Sent != 3 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats.  expected 3 sent and 1 dropped, actual %+v", stats)
+	}
+}
+
+func TestQueueOpenDropsUnreadableOperations(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "00000000000000000007.op"), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	dropped := make(chan queue.Operation, 1)
+	client := &fakeClient{}
+	q, err := queue.Open(dir, client, queue.Options{OnDrop: func(op queue.Operation, err error) {
+		dropped <- op
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	defer q.Close()
+
+	if op := <-dropped; op.Seq != 7 {
+		t.Errorf("unexpected dropped operation.  expected %v, actual %+v", 7, op)
+	}
+	failed, _ := filepath.Glob(filepath.Join(dir, "failed", "*.op"))
+	if len(failed) != 1 {
+		t.Errorf("unexpected failed operations.  expected %v, actual %v", 1, len(failed))
+	}
+
+	// The queue keeps working
+	if _, err := q.PushDocument(pushapi.Document{DocumentID: "file://a"}, "mysource"); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if err := q.Drain(); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	if stats := q.Stats(); stats.Sent != 1 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats.  expected 1 sent and 1 dropped, actual %+v", stats)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+
+	"github.com/coveo/go-coveo/auth"
+	"github.com/coveo/go-coveo/endpoint"
 )
 
 const (
@@ -25,29 +28,46 @@ type Client interface {
 
 // Config is used to configure a new client
 type Config struct {
-	Token     string
-	UserAgent string
+	Token string
+	// TokenSource provides the token of every request, Token is used if nil
+	TokenSource auth.TokenSource
+	UserAgent   string
 	// Endpoint is used if you want to use custom endpoints (dev,staging,testing)
 	Endpoint string
+	// Region is the region of the organization, used when Endpoint is empty
+	Region endpoint.Region
+	// Environment is the environment of the organization, used when Endpoint
+	// is empty
+	Environment endpoint.Environment
 }
 
 // NewClient returns a configured http search client using default http client
 func NewClient(c Config) (Client, error) {
 	if len(c.Endpoint) == 0 {
-		c.Endpoint = EndpointProduction
+		if len(c.Region) == 0 && len(c.Environment) == 0 {
+			c.Endpoint = EndpointProduction
+		} else {
+			resolved, err := endpoint.Resolve(endpoint.Search, c.Environment, c.Region)
+			if err != nil {
+				return nil, err
+			}
+			c.Endpoint = resolved
+		}
+	}
+
+	if c.TokenSource == nil {
+		c.TokenSource = auth.StaticToken(c.Token)
 	}
 
 	return &client{
-		token:      c.Token,
 		endpoint:   c.Endpoint,
-		httpClient: http.DefaultClient,
+		httpClient: auth.NewHTTPClient(c.TokenSource),
 		useragent:  c.UserAgent,
 	}, nil
 }
 
 type client struct {
 	httpClient *http.Client
-	token      string
 	endpoint   string
 	useragent  string
 }
@@ -64,7 +84,6 @@ func (c *client) Query(q Query) (*Response, error) {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+c.token)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accepts", "application/json")
 	req.Header.Set("User-Agent", c.useragent)
@@ -98,8 +117,6 @@ func (c *client) ListFacetValues(field string, maximumNumberOfValues int) (*Face
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+c.token)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
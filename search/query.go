@@ -14,6 +14,8 @@ type Query struct {
 	PartialMatchKeywords  int               `json:"partialMatchKeywords,omitempty"`
 	PartialMatchThreshold string            `json:"partialMatchThreshold,omitempty"`
 	Pipeline              string            `json:"pipeline,omitempty"`
+	SortCriteria          string            `json:"sortCriteria,omitempty"`
+	FieldsToInclude       []string          `json:"fieldsToInclude,omitempty"`
 }
 
 // GroupByRequest Struct representing a GroupByRequest send to the index. It is
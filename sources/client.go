@@ -0,0 +1,275 @@
+// Package sources manages the push sources and security providers of an
+// organization through the platform API. It is configured with the same
+// pushapi.Config used to push content to the sources.
+package sources
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/coveo/go-coveo/auth"
+	"github.com/coveo/go-coveo/endpoint"
+	"github.com/coveo/go-coveo/internal/httpapi"
+	"github.com/coveo/go-coveo/pushapi"
+)
+
+// Client is the sources client to manage the push sources of an organization
+type Client interface {
+	CreatePushSource(name string, visibility Visibility) (*Source, error)
+	Get(sourceID string) (*Source, error)
+	List() ([]Source, error)
+	Update(sourceID string, u Update) error
+	Delete(sourceID string) error
+	SetVisibility(sourceID string, visibility Visibility) error
+	Rebuild(sourceID string) error
+	Refresh(sourceID string) error
+	Status(sourceID string) (*Status, error)
+	Activities(sourceID string) ([]Activity, error)
+	PutSecurityProvider(p SecurityProvider) error
+	DeleteSecurityProvider(providerID string) error
+}
+
+// NewClient initializes a new sources client with the pushapi config, the
+// requests are sent to its PlatformEndpoint with its credentials. Like
+// pushapi.NewClient, the PlatformEndpoint is not resolved with a custom
+// Endpoint and no region or environment, it must then be given.
+func NewClient(c pushapi.Config) (Client, error) {
+	customEndpoint := len(c.Endpoint) != 0 && c.Endpoint != pushapi.EndpointProduction
+	if len(c.PlatformEndpoint) == 0 && customEndpoint && len(c.Region) == 0 && len(c.Environment) == 0 {
+		return nil, errors.New("You need a PlatformEndpoint with a custom Endpoint")
+	}
+
+	if len(c.PlatformEndpoint) == 0 {
+		resolved, err := endpoint.Resolve(endpoint.Platform, c.Environment, c.Region)
+		if err != nil {
+			return nil, err
+		}
+		c.PlatformEndpoint = resolved
+	}
+
+	if c.TokenSource == nil {
+		c.TokenSource = auth.StaticToken(c.APIKey)
+	}
+
+	return &client{
+		endpoint:       c.PlatformEndpoint,
+		organizationid: c.OrganizationID,
+		httpClient:     auth.NewHTTPClient(c.TokenSource),
+	}, nil
+}
+
+type client struct {
+	httpClient     *http.Client
+	endpoint       string
+	organizationid string
+}
+
+// CreatePushSource creates a push source and returns it with its ID
+func (c *client) CreatePushSource(name string, visibility Visibility) (*Source, error) {
+	if len(name) == 0 {
+		return nil, errors.New("You need a source name")
+	}
+	if len(visibility) == 0 {
+		visibility = VisibilitySecured
+	}
+
+	source := &Source{
+		Name:             name,
+		SourceType:       "PUSH",
+		SourceVisibility: visibility,
+		PushEnabled:      true,
+	}
+	if err := c.do("POST", c.url("sources"), source, source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// Get returns the source with its information
+func (c *client) Get(sourceID string) (*Source, error) {
+	if len(sourceID) == 0 {
+		return nil, errors.New("You need a sourceID")
+	}
+
+	source := &Source{}
+	if err := c.do("GET", c.url("sources/"+url.PathEscape(sourceID)), nil, source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// List returns every source of the organization
+func (c *client) List() ([]Source, error) {
+	sources := []Source{}
+	if err := c.do("GET", c.url("sources"), nil, &sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Update changes the settings of the source set in u. The other settings,
+// modeled by Source or not, are kept as they are.
+func (c *client) Update(sourceID string, u Update) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	changes := map[string]interface{}{}
+	if u.Name != nil {
+		changes["name"] = *u.Name
+	}
+	if u.SourceVisibility != nil {
+		changes["sourceVisibility"] = *u.SourceVisibility
+	}
+	if u.PushEnabled != nil {
+		changes["pushEnabled"] = *u.PushEnabled
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return c.updateRaw(sourceID, changes)
+}
+
+// Delete deletes the source and its documents
+func (c *client) Delete(sourceID string) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	return c.do("DELETE", c.url("sources/"+url.PathEscape(sourceID)), nil, nil)
+}
+
+// SetVisibility changes the visibility of the source, and only its visibility
+func (c *client) SetVisibility(sourceID string, visibility Visibility) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+
+	return c.updateRaw(sourceID, map[string]interface{}{
+		"sourceVisibility": visibility,
+	})
+}
+
+// updateRaw gets the source as raw JSON, replaces the changed settings and
+// puts it back. Source only models some of the settings of a source,
+// round-tripping the raw JSON keeps the other ones untouched.
+func (c *client) updateRaw(sourceID string, changes map[string]interface{}) error {
+	endpoint := c.url("sources/" + url.PathEscape(sourceID))
+
+	raw := map[string]json.RawMessage{}
+	if err := c.do("GET", endpoint, nil, &raw); err != nil {
+		return err
+	}
+	// The information is reported by the platform, it cannot be updated
+	delete(raw, "information")
+	for key, value := range changes {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		raw[key] = encoded
+	}
+	return c.do("PUT", endpoint, raw, nil)
+}
+
+// Rebuild triggers a rebuild of the source
+func (c *client) Rebuild(sourceID string) error {
+	return c.trigger(sourceID, "rebuild")
+}
+
+// Refresh triggers a refresh of the source
+func (c *client) Refresh(sourceID string) error {
+	return c.trigger(sourceID, "refresh")
+}
+
+// Status returns the current status of the source
+func (c *client) Status(sourceID string) (*Status, error) {
+	source, err := c.Get(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Information == nil {
+		return nil, fmt.Errorf("no status was returned for the source %s", sourceID)
+	}
+	return &source.Information.SourceStatus, nil
+}
+
+type activitiesPage struct {
+	Items []Activity `json:"items"`
+}
+
+// Activities returns the latest activities of the source
+func (c *client) Activities(sourceID string) ([]Activity, error) {
+	if len(sourceID) == 0 {
+		return nil, errors.New("You need a sourceID")
+	}
+
+	query := url.Values{}
+	query.Set("resourceId", sourceID)
+	query.Set("page", "0")
+	query.Set("perPage", "100")
+
+	page := activitiesPage{}
+	if err := c.do("GET", c.url("activities")+"?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// PutSecurityProvider creates or updates the security provider
+func (c *client) PutSecurityProvider(p SecurityProvider) error {
+	if len(p.ID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	return c.do("PUT", c.url("securityproviders/"+url.PathEscape(p.ID)), p, nil)
+}
+
+// DeleteSecurityProvider deletes the security provider
+func (c *client) DeleteSecurityProvider(providerID string) error {
+	if len(providerID) == 0 {
+		return errors.New("You need a providerID")
+	}
+	return c.do("DELETE", c.url("securityproviders/"+url.PathEscape(providerID)), nil, nil)
+}
+
+func (c *client) trigger(sourceID, operation string) error {
+	if len(sourceID) == 0 {
+		return errors.New("You need a sourceID")
+	}
+	return c.do("POST", c.url("sources/"+url.PathEscape(sourceID)+"/"+operation), nil, nil)
+}
+
+func (c *client) url(path string) string {
+	return fmt.Sprintf("%s%s/%s", c.endpoint, url.PathEscape(c.organizationid), path)
+}
+
+// do sends the request with in as JSON body, if not nil, and decodes the
+// response into out, if not nil
+func (c *client) do(method, endpoint string, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+
+	respBody, err := httpapi.Send(c.httpClient, req)
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
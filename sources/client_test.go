@@ -0,0 +1,158 @@
+package sources_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coveo/go-coveo/pushapi"
+	"github.com/coveo/go-coveo/sources"
+)
+
+// storedSource has settings Source does not model
+const storedSource = `{
+	"id": "mysource",
+	"name": "My source",
+	"sourceType": "PUSH",
+	"sourceVisibility": "SECURED",
+	"pushEnabled": true,
+	"preConversionExtensions": [{"extensionId": "myextension", "parameters": {}}],
+	"mappings": [{"id": "mapping1", "fieldName": "title", "content": ["%[title]"]}],
+	"information": {"sourceStatus": {"type": "IDLE"}, "numberOfDocuments": 42}
+}`
+
+// fakePlatform serves storedSource and records the last body put
+type fakePlatform struct {
+	*httptest.Server
+	put map[string]interface{}
+}
+
+func newFakePlatform(t *testing.T) *fakePlatform {
+	f := &fakePlatform{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/myorg/sources/mysource" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(storedSource))
+		case "PUT":
+			body, _ := ioutil.ReadAll(r.Body)
+			f.put = map[string]interface{}{}
+			if err := json.Unmarshal(body, &f.put); err != nil {
+				t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	return f
+}
+
+func (f *fakePlatform) client(t *testing.T) sources.Client {
+	c, err := sources.NewClient(pushapi.Config{
+		PlatformEndpoint: f.URL + "/",
+		OrganizationID:   "myorg",
+		APIKey:           "mykey",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	return c
+}
+
+func TestNewClientNeedsPlatformEndpoint(t *testing.T) {
+	_, err := sources.NewClient(pushapi.Config{Endpoint: "https://push.example.com/", OrganizationID: "myorg"})
+	if err == nil {
+		t.Errorf("expected an error with a custom Endpoint and no PlatformEndpoint")
+	}
+
+	_, err = sources.NewClient(pushapi.Config{Endpoint: pushapi.EndpointProduction, OrganizationID: "myorg"})
+	if err != nil {
+		t.Errorf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+}
+
+func assertUnknownFieldsKept(t *testing.T, put map[string]interface{}) {
+	if put == nil {
+		t.Fatalf("expected the source to be put")
+	}
+	for _, key := range []string{"preConversionExtensions", "mappings"} {
+		if _, ok := put[key]; !ok {
+			t.Errorf("expected %s to be kept, actual %v", key, put)
+		}
+	}
+	if _, ok := put["information"]; ok {
+		t.Errorf("expected the information not to be put, actual %v", put)
+	}
+}
+
+func TestSetVisibilityKeepsUnknownFields(t *testing.T) {
+	f := newFakePlatform(t)
+	defer f.Close()
+
+	if err := f.client(t).SetVisibility("mysource", sources.VisibilityShared); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	assertUnknownFieldsKept(t, f.put)
+	if f.put["sourceVisibility"] != "SHARED" {
+		t.Errorf("unexpected visibility.  expected %v, actual %v", "SHARED", f.put["sourceVisibility"])
+	}
+	if f.put["name"] != "My source" {
+		t.Errorf("unexpected name.  expected %v, actual %v", "My source", f.put["name"])
+	}
+}
+
+func TestUpdateKeepsUnknownFields(t *testing.T) {
+	f := newFakePlatform(t)
+	defer f.Close()
+
+	name := "Renamed"
+	visibility := sources.VisibilityPrivate
+	err := f.client(t).Update("mysource", sources.Update{
+		Name:             &name,
+		SourceVisibility: &visibility,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	assertUnknownFieldsKept(t, f.put)
+	if f.put["name"] != "Renamed" || f.put["sourceVisibility"] != "PRIVATE" {
+		t.Errorf("unexpected settings.  expected %v and %v, actual %v", "Renamed", "PRIVATE", f.put)
+	}
+}
+
+func TestUpdateOnlyName(t *testing.T) {
+	f := newFakePlatform(t)
+	defer f.Close()
+
+	name := "Renamed"
+	if err := f.client(t).Update("mysource", sources.Update{Name: &name}); err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	assertUnknownFieldsKept(t, f.put)
+	if f.put["name"] != "Renamed" {
+		t.Errorf("unexpected name.  expected %v, actual %v", "Renamed", f.put["name"])
+	}
+	if f.put["sourceVisibility"] != "SECURED" || f.put["pushEnabled"] != true || f.put["sourceType"] != "PUSH" {
+		t.Errorf("unexpected settings.  expected the stored ones, actual %v", f.put)
+	}
+}
+
+func TestUpdateUnknownSource(t *testing.T) {
+	f := newFakePlatform(t)
+	defer f.Close()
+
+	if err := f.client(t).SetVisibility("othersource", sources.VisibilityShared); err == nil {
+		t.Errorf("expected an error for an unknown source")
+	}
+	if f.put != nil {
+		t.Errorf("expected nothing to be put, actual %v", f.put)
+	}
+}
@@ -0,0 +1,99 @@
+package sources
+
+// Visibility controls who can see the documents of a source
+type Visibility string
+
+const (
+	// VisibilityShared makes the documents visible to everyone
+	VisibilityShared Visibility = "SHARED"
+	// VisibilityPrivate makes the documents visible to the specified
+	// identities only
+	VisibilityPrivate Visibility = "PRIVATE"
+	// VisibilitySecured makes the documents visible according to their
+	// permissions
+	VisibilitySecured Visibility = "SECURED"
+)
+
+// Source is a source of the organization
+type Source struct {
+	ID               string       `json:"id,omitempty"`
+	Name             string       `json:"name"`
+	SourceType       string       `json:"sourceType"`
+	SourceVisibility Visibility   `json:"sourceVisibility"`
+	PushEnabled      bool         `json:"pushEnabled"`
+	Information      *Information `json:"information,omitempty"`
+}
+
+// Update holds the settings of a source to change, the nil ones are kept
+type Update struct {
+	Name             *string
+	SourceVisibility *Visibility
+	PushEnabled      *bool
+}
+
+// Information is the state of a source, as reported by the platform
+type Information struct {
+	SourceStatus       Status `json:"sourceStatus"`
+	NumberOfDocuments  int64  `json:"numberOfDocuments"`
+	DocumentsTotalSize int64  `json:"documentsTotalSize"`
+	LastOperation      *struct {
+		OperationType string `json:"operationType"`
+		Result        string `json:"result"`
+		Timestamp     int64  `json:"timestamp"`
+	} `json:"lastOperation,omitempty"`
+}
+
+// Status is the current status of a source
+type Status struct {
+	Type              string   `json:"type"`
+	AllowedOperations []string `json:"allowedOperations"`
+}
+
+// Activity is an operation that happened on a source
+type Activity struct {
+	ID          string `json:"id"`
+	Operation   string `json:"operation"`
+	State       string `json:"state"`
+	Result      string `json:"result"`
+	CreatedDate int64  `json:"createdDate"`
+	Content     string `json:"content,omitempty"`
+}
+
+// SecurityProvider resolves the identities of the permissions of secured
+// sources
+type SecurityProvider struct {
+	ID                         string                             `json:"id"`
+	Name                       string                             `json:"name"`
+	Type                       string                             `json:"type"`
+	NodeRequired               bool                               `json:"nodeRequired"`
+	ReferencedBy               []ResourceReference                `json:"referencedBy,omitempty"`
+	CascadingSecurityProviders map[string]SecurityProviderCascade `json:"cascadingSecurityProviders,omitempty"`
+}
+
+// ResourceReference references a resource of the organization
+type ResourceReference struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// SecurityProviderCascade is a security provider the identities of a
+// security provider are expanded to
+type SecurityProviderCascade struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// NewPushSecurityProvider returns an expanded security provider for the push
+// source, cascading to the email security provider
+func NewPushSecurityProvider(id, sourceID string) SecurityProvider {
+	return SecurityProvider{
+		ID:           id,
+		Name:         id,
+		Type:         "EXPANDED",
+		NodeRequired: false,
+		ReferencedBy: []ResourceReference{{ID: sourceID, Type: "SOURCE"}},
+		CascadingSecurityProviders: map[string]SecurityProviderCascade{
+			"Email Security Provider": {ID: "Email Security Provider", Type: "EMAIL"},
+		},
+	}
+}